@@ -0,0 +1,259 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+)
+
+// ErrBudgetExceeded is returned (wrapped in *APIError) when the server
+// rejects an expense with 409; errors.Is also matches ledger.ErrBudgetExceeded.
+var ErrBudgetExceeded = ledger.ErrBudgetExceeded
+
+const defaultPageSize = 100
+
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ledger: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	if e.Code == ledger.ErrCodeBudgetExceeded {
+		return ledger.ErrBudgetExceeded
+	}
+	return nil
+}
+
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	maxRetries     int
+	retryBackoff   time.Duration
+	idempotencyKey func() string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout on a copy of the HTTP client, leaving one
+// passed to WithHTTPClient untouched.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// WithRetries retries GET requests and keyed POST requests on network
+// errors, 429 and 5xx, waiting backoff, 2*backoff, 4*backoff... between tries.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithIdempotencyKeys sets the generator for Idempotency-Key headers on
+// CreateTransaction. Passing nil disables the header, and with it POST retries.
+func WithIdempotencyKeys(generate func() string) Option {
+	return func(c *Client) {
+		c.idempotencyKey = generate
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		retryBackoff:   100 * time.Millisecond,
+		idempotencyKey: uuid.NewString,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) CreateTransaction(ctx context.Context, req ledger.CreateTransactionRequest) (*ledger.TransactionResponse, error) {
+	var key string
+	if c.idempotencyKey != nil {
+		key = c.idempotencyKey()
+	}
+
+	var resp ledger.TransactionResponse
+	if err := c.do(ctx, http.MethodPost, "/api/transactions", req, key, key != "", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ListTransactions(ctx context.Context, filter ledger.TransactionFilter) ([]ledger.TransactionResponse, error) {
+	path := "/api/transactions"
+	if query := filter.Query().Encode(); query != "" {
+		path += "?" + query
+	}
+
+	var resp []ledger.TransactionResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, "", true, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Transactions iterates over every transaction matching filter, fetching
+// pages of filter.Limit (or 100) items on demand. Iteration stops at the
+// first error, which is yielded with a zero transaction.
+func (c *Client) Transactions(ctx context.Context, filter ledger.TransactionFilter) iter.Seq2[ledger.TransactionResponse, error] {
+	return func(yield func(ledger.TransactionResponse, error) bool) {
+		if filter.Limit <= 0 {
+			filter.Limit = defaultPageSize
+		}
+
+		for {
+			page, err := c.ListTransactions(ctx, filter)
+			if err != nil {
+				yield(ledger.TransactionResponse{}, err)
+				return
+			}
+
+			for _, tx := range page {
+				if !yield(tx, nil) {
+					return
+				}
+			}
+
+			if len(page) < filter.Limit {
+				return
+			}
+			filter.Offset += len(page)
+		}
+	}
+}
+
+func (c *Client) SetBudget(ctx context.Context, req ledger.CreateBudgetRequest) (*ledger.BudgetResponse, error) {
+	var resp ledger.BudgetResponse
+	// Setting a budget overwrites it, so the call is safe to retry as-is.
+	if err := c.do(ctx, http.MethodPost, "/api/budgets", req, "", true, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ListBudgets(ctx context.Context) ([]ledger.BudgetResponse, error) {
+	var resp []ledger.BudgetResponse
+	if err := c.do(ctx, http.MethodGet, "/api/budgets", nil, "", true, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Health(ctx context.Context) error {
+	var resp map[string]string
+	if err := c.do(ctx, http.MethodGet, "/health", nil, "", true, &resp); err != nil {
+		return err
+	}
+	if resp["status"] != "ok" {
+		return fmt.Errorf("ledger: unhealthy status %q", resp["status"])
+	}
+	return nil
+}
+
+// do sends the request and, when retryable, repeats it on network errors,
+// 429 and 5xx up to maxRetries times.
+func (c *Client) do(ctx context.Context, method, path string, body any, idempotencyKey string, retryable bool, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryBackoff << (attempt - 1)):
+			}
+		}
+
+		var retry bool
+		retry, lastErr = c.attempt(ctx, method, path, payload, idempotencyKey, out)
+		if lastErr == nil || !retry || !retryable || attempt >= c.maxRetries {
+			return lastErr
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, idempotencyKey string, out any) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return false, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(ledger.IdempotencyKeyHeader, idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return isRetryableStatus(resp.StatusCode), decodeError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("ledger: decode response: %w", err)
+	}
+	return false, nil
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var errResp ledger.ErrorResponse
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &errResp); err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+		apiErr.Code = errResp.Code
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+)
+
+func newLedgerServer(t *testing.T, wrap func(http.Handler) http.Handler) (*httptest.Server, *ledger.Ledger) {
+	t.Helper()
+
+	ledgerService := ledger.NewLedger()
+	handler := ledger.NewHandler(ledgerService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	mux.HandleFunc("GET /api/transactions", handler.ListTransactionsHandler)
+	mux.HandleFunc("POST /api/budgets", handler.CreateBudgetHandler)
+	mux.HandleFunc("GET /api/budgets", handler.ListBudgetsHandler)
+	mux.HandleFunc("GET /health", handler.HealthHandler)
+
+	var h http.Handler = mux
+	if wrap != nil {
+		h = wrap(h)
+	}
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server, ledgerService
+}
+
+func TestClient_TransactionsAndBudgets(t *testing.T) {
+	server, _ := newLedgerServer(t, nil)
+	c := New(server.URL)
+	ctx := context.Background()
+
+	if err := c.Health(ctx); err != nil {
+		t.Fatalf("Expected healthy server, got %v", err)
+	}
+
+	budget, err := c.SetBudget(ctx, ledger.CreateBudgetRequest{Category: "food", Limit: 1000})
+	if err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	if budget.Limit != 1000 {
+		t.Errorf("Expected limit 1000, got %f", budget.Limit)
+	}
+
+	tx, err := c.CreateTransaction(ctx, ledger.CreateTransactionRequest{
+		Amount: 400, Category: "food", Date: "2024-01-15", Type: "expense",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if tx.ID == "" || tx.Amount != 400 {
+		t.Errorf("Unexpected transaction %+v", tx)
+	}
+
+	_, err = c.CreateTransaction(ctx, ledger.CreateTransactionRequest{
+		Amount: 700, Category: "food", Date: "2024-01-16", Type: "expense",
+	})
+	if !errors.Is(err, ledger.ErrBudgetExceeded) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected budget exceeded error, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected *APIError with status 409, got %v", err)
+	}
+
+	_, err = c.SetBudget(ctx, ledger.CreateBudgetRequest{Category: "food", Limit: -1})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected *APIError with status 400, got %v", err)
+	}
+	if errors.Is(err, ErrBudgetExceeded) {
+		t.Error("Validation error must not match ErrBudgetExceeded")
+	}
+
+	budgets, err := c.ListBudgets(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list budgets: %v", err)
+	}
+	if len(budgets) != 1 || budgets[0].Spent != 400 {
+		t.Errorf("Expected one budget with 400 spent, got %+v", budgets)
+	}
+}
+
+func TestClient_ListTransactionsPagination(t *testing.T) {
+	server, _ := newLedgerServer(t, nil)
+	c := New(server.URL)
+	ctx := context.Background()
+
+	for i := 1; i <= 25; i++ {
+		txType := "expense"
+		if i%5 == 0 {
+			txType = "income"
+		}
+		_, err := c.CreateTransaction(ctx, ledger.CreateTransactionRequest{
+			Amount: float64(i), Category: "food", Date: fmt.Sprintf("2024-01-%02d", i), Type: txType,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create transaction %d: %v", i, err)
+		}
+	}
+
+	t.Run("filters", func(t *testing.T) {
+		txs, err := c.ListTransactions(ctx, ledger.TransactionFilter{
+			Type: "income",
+			From: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(txs) != 3 {
+			t.Errorf("Expected 3 income transactions between 10th and 20th, got %d", len(txs))
+		}
+	})
+
+	t.Run("iterator", func(t *testing.T) {
+		var count int
+		for tx, err := range c.Transactions(ctx, ledger.TransactionFilter{Type: "expense", Limit: 7}) {
+			if err != nil {
+				t.Fatalf("Iteration failed: %v", err)
+			}
+			if tx.Type != "expense" {
+				t.Errorf("Expected expense, got %s", tx.Type)
+			}
+			count++
+		}
+		if count != 20 {
+			t.Errorf("Expected 20 expenses, got %d", count)
+		}
+	})
+
+	t.Run("iterator stops early", func(t *testing.T) {
+		var count int
+		for range c.Transactions(ctx, ledger.TransactionFilter{Limit: 10}) {
+			count++
+			if count == 3 {
+				break
+			}
+		}
+		if count != 3 {
+			t.Errorf("Expected to stop after 3, got %d", count)
+		}
+	})
+}
+
+func TestClient_Retries(t *testing.T) {
+	var requests, failures atomic.Int32
+	failFirst := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			if failures.Load() < 2 {
+				failures.Add(1)
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server, ledgerService := newLedgerServer(t, failFirst)
+	ctx := context.Background()
+
+	t.Run("idempotent create is retried once applied", func(t *testing.T) {
+		c := New(server.URL, WithRetries(3, time.Millisecond))
+		tx, err := c.CreateTransaction(ctx, ledger.CreateTransactionRequest{
+			Amount: 10, Category: "food", Date: "2024-01-15", Type: "expense",
+		})
+		if err != nil {
+			t.Fatalf("Expected retry to succeed, got %v", err)
+		}
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+		if n := len(ledgerService.ListTransactions()); n != 1 {
+			t.Errorf("Expected 1 stored transaction despite retries, got %d", n)
+		}
+		if ledgerService.ListTransactions()[0].ID != tx.ID {
+			t.Errorf("Expected replayed response to carry the original ID")
+		}
+	})
+
+	t.Run("create without idempotency key is not retried", func(t *testing.T) {
+		failures.Store(0)
+		requests.Store(0)
+
+		c := New(server.URL, WithRetries(3, time.Millisecond), WithIdempotencyKeys(nil))
+		_, err := c.CreateTransaction(ctx, ledger.CreateTransactionRequest{
+			Amount: 10, Category: "food", Date: "2024-01-15", Type: "expense",
+		})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 error, got %v", err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", requests.Load())
+		}
+	})
+}
+
+func TestClient_Timeout(t *testing.T) {
+	slow := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server, _ := newLedgerServer(t, slow)
+	shared := &http.Client{}
+	c := New(server.URL, WithHTTPClient(shared), WithTimeout(20*time.Millisecond))
+
+	if _, err := c.ListBudgets(context.Background()); err == nil {
+		t.Error("Expected timeout error")
+	}
+	if shared.Timeout != 0 {
+		t.Errorf("Expected the caller's client to be left alone, got timeout %v", shared.Timeout)
+	}
+}
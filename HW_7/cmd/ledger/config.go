@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is resolved with increasing precedence from defaults, the YAML file
+// named by -config or LEDGER_CONFIG, LEDGER_* environment variables and
+// command-line flags.
+type Config struct {
+	Addr     string `yaml:"addr"`
+	GRPCAddr string `yaml:"grpc_addr"`
+
+	StorageDSN    string `yaml:"storage_dsn"`
+	AttachmentDir string `yaml:"attachment_dir"`
+
+	LogLevel      string `yaml:"log_level"`
+	LogFormat     string `yaml:"log_format"`
+	TraceExporter string `yaml:"trace_exporter"`
+
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+
+	// AdminTokens holds comma-separated name:token pairs for admin
+	// operations such as closing and reopening periods.
+	AdminTokens string `yaml:"admin_tokens"`
+
+	// Validation limits on top of the default rules; zero values leave a
+	// limit off. AllowedCategories is a comma-separated list.
+	MaxAmount            float64       `yaml:"max_amount"`
+	AllowedCategories    string        `yaml:"allowed_categories"`
+	MaxDescriptionLength int           `yaml:"max_description_length"`
+	MaxDateAge           time.Duration `yaml:"max_date_age"`
+	MaxDateAhead         time.Duration `yaml:"max_date_ahead"`
+
+	// Rate limits per client and route, as requests per RateLimitPeriod; zero
+	// disables a limit. APIKeys holds comma-separated name:key pairs of
+	// clients limited by API key rather than IP address.
+	RateLimit            int           `yaml:"rate_limit"`
+	TransactionRateLimit int           `yaml:"transaction_rate_limit"`
+	RateLimitPeriod      time.Duration `yaml:"rate_limit_period"`
+	APIKeys              string        `yaml:"api_keys"`
+
+	// PostInterval is how often pending transactions are checked for
+	// posting on their date.
+	PostInterval time.Duration `yaml:"post_interval"`
+
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay"`
+	// FlushTimeout bounds saving the ledger and exporting traces once the
+	// servers have stopped, however long draining took.
+	FlushTimeout time.Duration `yaml:"flush_timeout"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:                 ":8080",
+		GRPCAddr:             ":9090",
+		StorageDSN:           "memory:",
+		LogLevel:             "info",
+		LogFormat:            "json",
+		TraceExporter:        "none",
+		MaxDateAhead:         90 * 24 * time.Hour,
+		RateLimit:            120,
+		RateLimitPeriod:      time.Minute,
+		TransactionRateLimit: 30,
+		PostInterval:         time.Minute,
+		ReadHeaderTimeout:    5 * time.Second,
+		ReadTimeout:          15 * time.Second,
+		WriteTimeout:         30 * time.Second,
+		IdleTimeout:          2 * time.Minute,
+		ShutdownTimeout:      20 * time.Second,
+		FlushTimeout:         10 * time.Second,
+	}
+}
+
+const envPrefix = "LEDGER_"
+
+func bindFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "HTTP listen address")
+	fs.StringVar(&c.GRPCAddr, "grpc-addr", c.GRPCAddr, "gRPC listen address")
+	fs.StringVar(&c.StorageDSN, "storage-dsn", c.StorageDSN, "storage DSN: memory: or file:/path/to/ledger.json")
+	fs.StringVar(&c.AttachmentDir, "attachment-dir", c.AttachmentDir, "directory for transaction attachments; kept in memory when empty")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log output format: json or text")
+	fs.StringVar(&c.TraceExporter, "trace-exporter", c.TraceExporter, "trace exporter: none, stdout or otlp")
+	fs.StringVar(&c.TLSCertFile, "tls-cert", c.TLSCertFile, "TLS certificate file; serves HTTPS when set with -tls-key")
+	fs.StringVar(&c.TLSKeyFile, "tls-key", c.TLSKeyFile, "TLS private key file")
+	fs.StringVar(&c.AdminTokens, "admin-tokens", c.AdminTokens, "comma-separated name:token pairs allowed to close and reopen periods")
+	fs.Float64Var(&c.MaxAmount, "max-amount", c.MaxAmount, "largest accepted transaction amount; unlimited when 0")
+	fs.StringVar(&c.AllowedCategories, "allowed-categories", c.AllowedCategories, "comma-separated categories accepted for transactions and budgets; any when empty")
+	fs.IntVar(&c.MaxDescriptionLength, "max-description-length", c.MaxDescriptionLength, "longest accepted transaction description in characters; unlimited when 0")
+	fs.DurationVar(&c.MaxDateAge, "max-date-age", c.MaxDateAge, "how far back transactions may be dated; unlimited when 0")
+	fs.DurationVar(&c.MaxDateAhead, "max-date-ahead", c.MaxDateAhead, "how far ahead transactions may be scheduled; none when 0")
+	fs.IntVar(&c.RateLimit, "rate-limit", c.RateLimit, "requests per client and route each rate-limit-period; unlimited when 0")
+	fs.IntVar(&c.TransactionRateLimit, "transaction-rate-limit", c.TransactionRateLimit, "transactions a client may create each rate-limit-period; unlimited when 0")
+	fs.DurationVar(&c.RateLimitPeriod, "rate-limit-period", c.RateLimitPeriod, "period the rate limits refill over")
+	fs.StringVar(&c.APIKeys, "api-keys", c.APIKeys, "comma-separated name:key pairs of clients rate limited by X-API-Key")
+	fs.DurationVar(&c.PostInterval, "post-interval", c.PostInterval, "how often pending transactions are posted once due")
+	fs.DurationVar(&c.ReadHeaderTimeout, "read-header-timeout", c.ReadHeaderTimeout, "time allowed to read request headers")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "time allowed to read a whole request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "time allowed to write a response")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "how long keep-alive connections stay idle")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "how long shutdown waits for in-flight requests")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "how long to report not-ready before draining")
+	fs.DurationVar(&c.FlushTimeout, "flush-timeout", c.FlushTimeout, "time allowed to save the ledger and export traces on shutdown")
+}
+
+// envName maps a flag name to its environment variable, e.g. "grpc-addr" to
+// LEDGER_GRPC_ADDR.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	parsed := flag.NewFlagSet("ledger", flag.ContinueOnError)
+	configPath := parsed.String("config", "", "path to a YAML config file (env "+envPrefix+"CONFIG)")
+	var fromFlags Config
+	bindFlags(parsed, &fromFlags)
+	if err := parsed.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := defaultConfig()
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		if err := loadConfigFile(path, &config); err != nil {
+			return Config{}, err
+		}
+	}
+
+	// Environment and flags are applied through a flag set bound to config,
+	// so both share the flag parsers.
+	resolved := flag.NewFlagSet("ledger", flag.ContinueOnError)
+	bindFlags(resolved, &config)
+
+	var errs []error
+	resolved.VisitAll(func(f *flag.Flag) {
+		if value, ok := lookupEnv(envName(f.Name)); ok {
+			if err := resolved.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", envName(f.Name), err))
+			}
+		}
+	})
+	parsed.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			resolved.Set(f.Name, f.Value.String())
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	return config, config.validate()
+}
+
+func loadConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	if _, err := c.slogLevel(); err != nil {
+		return err
+	}
+	if _, err := ledger.ParseAdminTokens(c.AdminTokens); err != nil {
+		return err
+	}
+	if c.MaxAmount < 0 || c.MaxDescriptionLength < 0 || c.MaxDateAge < 0 || c.MaxDateAhead < 0 {
+		return errors.New("validation limits cannot be negative")
+	}
+	if _, err := ledger.ParseAPIKeys(c.APIKeys); err != nil {
+		return err
+	}
+	if c.RateLimit < 0 || c.TransactionRateLimit < 0 {
+		return errors.New("rate limits cannot be negative")
+	}
+	if c.RateLimitPeriod <= 0 {
+		return errors.New("rate-limit-period must be positive")
+	}
+	if c.PostInterval <= 0 {
+		return errors.New("post-interval must be positive")
+	}
+	if c.FlushTimeout <= 0 {
+		return errors.New("flush-timeout must be positive")
+	}
+	return nil
+}
+
+// validationPolicy adds the configured limits to the default rules.
+func (c Config) validationPolicy() ledger.ValidationPolicy {
+	policy := ledger.DefaultValidationPolicy()
+	policy.Transaction = policy.Transaction.With(ledger.DateWindow(c.MaxDateAge, c.MaxDateAhead))
+	if c.MaxAmount > 0 {
+		policy.Transaction = policy.Transaction.With(ledger.MaxAmount(c.MaxAmount))
+	}
+	if c.MaxDescriptionLength > 0 {
+		policy.Transaction = policy.Transaction.With(ledger.MaxDescriptionLength(c.MaxDescriptionLength))
+	}
+
+	var categories []string
+	for _, category := range strings.Split(c.AllowedCategories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) > 0 {
+		policy.Transaction = policy.Transaction.With(ledger.AllowedCategories(categories...))
+		policy.Budget = policy.Budget.With(ledger.AllowedBudgetCategories(categories...))
+	}
+	return policy
+}
+
+// rateLimitConfig applies the configured limits to the default rate limit
+// config, resolving routes through mux.
+func (c Config) rateLimitConfig(mux *http.ServeMux) ledger.RateLimitConfig {
+	config := ledger.DefaultRateLimitConfig()
+	config.Default = ledger.RateLimit{Requests: c.RateLimit, Period: c.RateLimitPeriod}
+	config.LimitTransactions(ledger.RateLimit{Requests: c.TransactionRateLimit, Period: c.RateLimitPeriod})
+	config.Mux = mux
+	keys, _ := ledger.ParseAPIKeys(c.APIKeys)
+	config.KeyFunc = ledger.APIKeyClientKey(keys)
+	return config
+}
+
+func (c Config) slogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", c.LogLevel)
+	}
+	return level, nil
+}
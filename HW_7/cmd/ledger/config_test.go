@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ledger.yaml")
+	content := "addr: \":7000\"\nlog_level: debug\nstorage_dsn: file:/var/lib/ledger.json\nwrite_timeout: 1m\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, c Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c Config) {
+				if c != defaultConfig() {
+					t.Errorf("Expected defaults, got %+v", c)
+				}
+			},
+		},
+		{
+			name: "file overrides defaults",
+			args: []string{"-config", file},
+			check: func(t *testing.T, c Config) {
+				if c.Addr != ":7000" || c.LogLevel != "debug" || c.WriteTimeout != time.Minute {
+					t.Errorf("Expected file values, got %+v", c)
+				}
+				if c.ReadTimeout != defaultConfig().ReadTimeout {
+					t.Errorf("Expected unset keys to keep defaults, got %v", c.ReadTimeout)
+				}
+			},
+		},
+		{
+			name: "env overrides file",
+			env:  map[string]string{"LEDGER_CONFIG": file, "LEDGER_ADDR": ":7100", "LEDGER_WRITE_TIMEOUT": "5s"},
+			check: func(t *testing.T, c Config) {
+				if c.Addr != ":7100" || c.WriteTimeout != 5*time.Second {
+					t.Errorf("Expected env values, got %+v", c)
+				}
+				if c.StorageDSN != "file:/var/lib/ledger.json" {
+					t.Errorf("Expected file storage DSN, got %s", c.StorageDSN)
+				}
+			},
+		},
+		{
+			name: "flags override env",
+			args: []string{"-config", file, "-addr", ":7200"},
+			env:  map[string]string{"LEDGER_ADDR": ":7100", "LEDGER_LOG_LEVEL": "warn"},
+			check: func(t *testing.T, c Config) {
+				if c.Addr != ":7200" || c.LogLevel != "warn" {
+					t.Errorf("Expected flag addr and env log level, got %+v", c)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+
+			config, err := loadConfig(tt.args, lookupEnv)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.yaml")
+	os.WriteFile(unknown, []byte("port: 8080\n"), 0o600)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"missing file", []string{"-config", filepath.Join(dir, "missing.yaml")}, nil},
+		{"unknown file key", []string{"-config", unknown}, nil},
+		{"invalid env duration", nil, map[string]string{"LEDGER_IDLE_TIMEOUT": "soon"}},
+		{"invalid log level", []string{"-log-level", "loud"}, nil},
+		{"tls cert without key", []string{"-tls-cert", "cert.pem"}, nil},
+		{"invalid admin token", nil, map[string]string{"LEDGER_ADMIN_TOKENS": "alice"}},
+		{"negative max amount", []string{"-max-amount", "-5"}, nil},
+		{"zero post interval", []string{"-post-interval", "0s"}, nil},
+		{"zero flush timeout", []string{"-flush-timeout", "0s"}, nil},
+		{"invalid API key", []string{"-api-keys", "billing"}, nil},
+		{"negative rate limit", []string{"-rate-limit", "-1"}, nil},
+		{"zero rate limit period", nil, map[string]string{"LEDGER_RATE_LIMIT_PERIOD": "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+
+			if _, err := loadConfig(tt.args, lookupEnv); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestConfig_RateLimitConfig(t *testing.T) {
+	config, err := loadConfig([]string{"-rate-limit", "10", "-transaction-rate-limit", "0", "-api-keys", "billing:secret"}, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limits := config.rateLimitConfig(http.NewServeMux())
+
+	if limits.Default != (ledger.RateLimit{Requests: 10, Period: time.Minute}) {
+		t.Errorf("Expected 10 requests a minute, got %+v", limits.Default)
+	}
+	for _, route := range ledger.TransactionRoutes {
+		if limit, exists := limits.Routes[route]; !exists || limit.Requests != 0 {
+			t.Errorf("%s: expected transactions to be unlimited, got %+v", route, limit)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/budgets", nil)
+	req.Header.Set(ledger.APIKeyHeader, "secret")
+	if key := limits.KeyFunc(req); key != "key:billing" {
+		t.Errorf("Expected the configured client, got %q", key)
+	}
+}
+
+func TestConfig_ValidationPolicy(t *testing.T) {
+	config, err := loadConfig([]string{"-max-amount", "500", "-allowed-categories", "food, rent", "-max-date-ahead", "720h"}, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	policy := config.validationPolicy()
+
+	tests := []struct {
+		name    string
+		tx      ledger.Transaction
+		wantErr bool
+	}{
+		{"within limits", ledger.Transaction{Amount: 120, Category: "rent", Date: time.Now(), Type: "expense"}, false},
+		{"scheduled", ledger.Transaction{Amount: 120, Category: "rent", Date: time.Now().AddDate(0, 0, 14), Type: "expense"}, false},
+		{"too far ahead", ledger.Transaction{Amount: 120, Category: "rent", Date: time.Now().AddDate(0, 2, 0), Type: "expense"}, true},
+		{"over max amount", ledger.Transaction{Amount: 501, Category: "rent", Date: time.Now(), Type: "expense"}, true},
+		{"category not allowed", ledger.Transaction{Amount: 10, Category: "travel", Date: time.Now(), Type: "expense"}, true},
+	}
+	for _, tt := range tests {
+		if err := policy.Transaction.Validate(&tt.tx); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	if err := policy.Budget.Validate(&ledger.Budget{Category: "travel", Limit: 100}); err == nil {
+		t.Error("Expected budget category to be restricted")
+	}
+}
@@ -1,38 +1,269 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/jukov801/Golang_MIPT/HW_6/gateway/internal/api"
 	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	config, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(config); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(config Config) error {
+	level, _ := config.slogLevel()
+	slog.SetDefault(ledger.NewLogger(os.Stdout, config.LogFormat, level))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	shutdownTracing, err := ledger.SetupTracing(ctx, config.TraceExporter, "ledger", os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	storage, err := ledger.OpenStorage(config.StorageDSN)
+	if err != nil {
+		return err
+	}
+
 	ledgerService := ledger.NewLedger()
-	handler := api.NewHandler(ledgerService)
+	ledgerService.SetValidationPolicy(config.validationPolicy())
+	if config.AttachmentDir != "" {
+		blobs, err := ledger.NewLocalBlobStore(config.AttachmentDir)
+		if err != nil {
+			return fmt.Errorf("open attachment store: %w", err)
+		}
+		ledgerService.SetBlobStore(blobs)
+	}
+	if err := storage.Load(ctx, ledgerService); err != nil {
+		return fmt.Errorf("load storage: %w", err)
+	}
+	go ledgerService.PostScheduled(ctx, config.PostInterval)
+	handler := ledger.NewHandler(ledgerService)
+	admins, err := ledger.ParseAdminTokens(config.AdminTokens)
+	if err != nil {
+		return err
+	}
+	handler.SetAdminTokens(admins)
+
+	health := ledger.NewHealth()
+	health.Register("storage", storage.Ping)
+
+	metrics := ledger.NewMetrics()
+	metrics.Instrument(ledgerService)
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	mux.HandleFunc("POST /api/transactions:batch", handler.CreateTransactionsBatchHandler)
 	mux.HandleFunc("GET /api/transactions", handler.ListTransactionsHandler)
+	mux.HandleFunc("GET /api/anomalies", handler.ListAnomaliesHandler)
+	mux.HandleFunc("GET /api/events", handler.EventsHandler)
+	mux.HandleFunc("PUT /api/transactions/{id}/status", handler.SetTransactionStatusHandler)
+
+	mux.HandleFunc("POST /api/transactions/{id}/attachments", handler.UploadAttachmentHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments", handler.ListAttachmentsHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments/{attachment}", handler.DownloadAttachmentHandler)
+	mux.HandleFunc("DELETE /api/transactions/{id}/attachments/{attachment}", handler.DeleteAttachmentHandler)
 
 	mux.HandleFunc("POST /api/budgets", handler.CreateBudgetHandler)
 	mux.HandleFunc("GET /api/budgets", handler.ListBudgetsHandler)
+	mux.HandleFunc("GET /api/budgets/{category}/forecast", handler.ForecastBudgetHandler)
+
+	mux.HandleFunc("POST /api/goals", handler.CreateGoalHandler)
+	mux.HandleFunc("GET /api/goals", handler.ListGoalsHandler)
+	mux.HandleFunc("GET /api/goals/{id}", handler.GetGoalHandler)
+	mux.HandleFunc("PUT /api/goals/{id}", handler.UpdateGoalHandler)
+	mux.HandleFunc("DELETE /api/goals/{id}", handler.DeleteGoalHandler)
+
+	mux.HandleFunc("GET /api/summary", handler.SummaryHandler)
+	mux.HandleFunc("GET /api/reports/tags", handler.TagReportHandler)
+
+	mux.HandleFunc("GET /api/balances", handler.BalancesHandler)
+	mux.HandleFunc("POST /api/balances/settlements", handler.CreateSettlementHandler)
+
+	mux.HandleFunc("POST /api/reconciliations", handler.CreateReconciliationHandler)
+	mux.HandleFunc("GET /api/reconciliations", handler.ListReconciliationsHandler)
+	mux.HandleFunc("GET /api/reconciliations/{id}", handler.GetReconciliationHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/matches", handler.MatchStatementLineHandler)
+	mux.HandleFunc("DELETE /api/reconciliations/{id}/matches/{line}", handler.UnmatchStatementLineHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/complete", handler.CompleteReconciliationHandler)
+
+	mux.HandleFunc("GET /api/periods", handler.ListClosedPeriodsHandler)
+	mux.HandleFunc("GET /api/periods/audit", handler.PeriodAuditHandler)
+	mux.HandleFunc("POST /api/periods/{period}/close", handler.ClosePeriodHandler)
+	mux.HandleFunc("POST /api/periods/{period}/reopen", handler.ReopenPeriodHandler)
 
 	mux.HandleFunc("GET /health", handler.HealthHandler)
+	mux.HandleFunc("GET /health/live", health.LiveHandler)
+	mux.HandleFunc("GET /health/ready", health.ReadyHandler)
+
+	mux.HandleFunc("GET /openapi.json", ledger.OpenAPIHandler)
+	mux.HandleFunc("GET /docs", ledger.DocsHandler)
+	mux.HandleFunc("GET /{$}", handler.DashboardHandler)
+	mux.HandleFunc("POST /{$}", handler.DashboardHandler)
+	mux.Handle("GET /static/", ledger.DashboardAssetsHandler())
 
-	handlerWithMiddleware := ledger.LoggingMiddleware(mux)
+	mux.Handle("GET /metrics", metrics.Handler())
 
-	port := ":8080"
-	fmt.Printf("Ledger server starting on http://localhost%s\n", port)
+	rateLimit := ledger.RateLimitMiddleware(config.rateLimitConfig(mux))
+	handlerWithMiddleware := ledger.LoggingMiddleware(ledger.TracingMiddleware(metrics.Middleware(rateLimit(mux))))
+
+	server := &http.Server{
+		Addr:              config.Addr,
+		Handler:           handlerWithMiddleware,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	// Event streams stay open until the client leaves; end them so Shutdown
+	// can drain.
+	server.RegisterOnShutdown(ledgerService.CloseEvents)
+
+	scheme := "http"
+	if config.TLSCertFile != "" {
+		scheme = "https"
+	}
+	fmt.Printf("Ledger server starting on %s://localhost%s\n", scheme, config.Addr)
 	fmt.Println("Available endpoints:")
 	fmt.Println("  POST /api/transactions - Create transaction")
+	fmt.Println("  POST /api/transactions:batch - Create transactions in bulk (atomic or best_effort)")
 	fmt.Println("  GET  /api/transactions - List transactions")
+	fmt.Println("  GET  /api/anomalies    - List flagged transactions")
+	fmt.Println("  GET  /api/events       - Live feed of ledger changes (SSE)")
+	fmt.Println("  PUT  /api/transactions/{id}/status - Mark cleared or uncleared")
+	fmt.Println("  POST /api/transactions/{id}/attachments - Upload receipt")
+	fmt.Println("  GET  /api/transactions/{id}/attachments - List attachments")
+	fmt.Println("  GET|DELETE /api/transactions/{id}/attachments/{attachment} - Download or delete attachment")
 	fmt.Println("  POST /api/budgets      - Create budget")
 	fmt.Println("  GET  /api/budgets      - List budgets")
+	fmt.Println("  GET  /api/budgets/{category}/forecast - Forecast month-end spending")
+	fmt.Println("  POST /api/goals        - Create savings goal")
+	fmt.Println("  GET  /api/goals        - List savings goals")
+	fmt.Println("  GET|PUT|DELETE /api/goals/{id} - Manage savings goal")
+	fmt.Println("  GET  /api/summary      - Monthly summary report")
+	fmt.Println("  GET  /api/reports/tags - Totals grouped by tag")
+	fmt.Println("  GET  /api/balances     - Who owes whom, with a settle-up plan")
+	fmt.Println("  POST /api/balances/settlements - Record a settlement")
+	fmt.Println("  POST /api/reconciliations - Reconcile a bank statement")
+	fmt.Println("  GET  /api/reconciliations[/{id}] - Reconciliation status")
+	fmt.Println("  POST|DELETE /api/reconciliations/{id}/matches[/{line}] - Match or unmatch a line")
+	fmt.Println("  POST /api/reconciliations/{id}/complete - Complete and lock the period")
+	fmt.Println("  GET  /api/periods      - List closed periods")
+	fmt.Println("  GET  /api/periods/audit - Period close and reopen history")
+	fmt.Println("  POST /api/periods/{YYYY-MM}/close - Close a period (admin)")
+	fmt.Println("  POST /api/periods/{YYYY-MM}/reopen - Reopen a period (admin)")
 	fmt.Println("  GET  /health           - Health check")
+	fmt.Println("  GET  /health/live      - Liveness probe")
+	fmt.Println("  GET  /health/ready     - Readiness probe")
+	fmt.Println("  GET  /openapi.json     - OpenAPI specification")
+	fmt.Println("  GET  /docs             - API documentation")
+	fmt.Println("  GET  /                 - Web dashboard")
+	fmt.Println("  GET  /metrics          - Prometheus metrics")
+
+	grpcServer := grpc.NewServer()
+	ledger.NewGRPCServer(ledgerService).Register(grpcServer)
+
+	listener, err := net.Listen("tcp", config.GRPCAddr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Ledger gRPC server starting on localhost%s\n", config.GRPCAddr)
+
+	httpListener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- grpcServer.Serve(listener)
+	}()
+	go func() {
+		var err error
+		if config.TLSCertFile != "" {
+			err = server.ServeTLS(httpListener, config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = server.Serve(httpListener)
+		}
+		serveErr <- err
+	}()
+
+	// Both listeners are bound and storage is loaded, so traffic can flow.
+	health.SetReady()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown requested, draining connections", "timeout", config.ShutdownTimeout)
+	case err := <-serveErr:
+		slog.Error("server stopped unexpectedly", "error", err)
+	}
+
+	// Report not-ready for a while before draining, so load balancers stop
+	// sending new requests while the listener is still open.
+	health.SetShuttingDown()
+	time.Sleep(config.ShutdownDelay)
+
+	return shutdown(config, server, grpcServer, func(ctx context.Context) error {
+		return errors.Join(storage.Flush(ctx, ledgerService), shutdownTracing(ctx))
+	})
+}
+
+// shutdown stops accepting connections, waits for in-flight HTTP requests and
+// gRPC calls up to the shutdown timeout, then runs flush with its own timeout
+// so draining cannot use up its time.
+func shutdown(config Config, server *http.Server, grpcServer *grpc.Server, flush func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http shutdown: %w", err))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), config.FlushTimeout)
+	defer cancelFlush()
+	if err := flush(flushCtx); err != nil {
+		errs = append(errs, fmt.Errorf("flush: %w", err))
+	}
 
-	log.Fatal(http.ListenAndServe(port, handlerWithMiddleware))
+	slog.Info("shutdown complete")
+	return errors.Join(errs...)
 }
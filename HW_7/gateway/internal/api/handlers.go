@@ -1,188 +1,527 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/jukov801/ledger/ledger"
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
 )
 
-type Handler struct {
-	ledger *ledger.Ledger
-}
+const TenantHeader = "X-Tenant-ID"
 
-func NewHandler(ledger *ledger.Ledger) *Handler {
-	return &Handler{ledger: ledger}
-}
+const (
+	// maxUpstreamBody caps how much of an upstream response the gateway
+	// buffers.
+	maxUpstreamBody = 10 << 20
+	// maxRequestBody caps how much of a client request the gateway buffers
+	// for forwarding.
+	maxRequestBody = maxUpstreamBody
+)
 
-type CreateTransactionRequest struct {
-	Amount      float64 `json:"amount"`
-	Category    string  `json:"category"`
-	Description string  `json:"description,omitempty"`
-	Date        string  `json:"date"` // ISO format "2006-01-02"
-	Type        string  `json:"type"` // "income" or "expense"
+var (
+	errUpstreamUnavailable = errors.New("upstream unavailable")
+	errUpstreamTooLarge    = errors.New("upstream response too large")
+)
+
+type Config struct {
+	Upstreams        []string
+	Timeout          time.Duration
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
-type TransactionResponse struct {
-	ID          string    `json:"id"`
-	Amount      float64   `json:"amount"`
-	Category    string    `json:"category"`
-	Description string    `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Type        string    `json:"type"`
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          5 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     100 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+	}
 }
 
-type CreateBudgetRequest struct {
-	Category string  `json:"category"`
-	Limit    float64 `json:"limit"`
+type upstream struct {
+	baseURL *url.URL
+	breaker *circuitBreaker
 }
 
-type BudgetResponse struct {
-	Category string  `json:"category"`
-	Limit    float64 `json:"limit"`
-	Spent    float64 `json:"spent"`
+type Handler struct {
+	upstreams []*upstream
+	client    *http.Client
+	config    Config
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+func NewHandler(config Config) (*Handler, error) {
+	if len(config.Upstreams) == 0 {
+		return nil, errors.New("at least one upstream is required")
+	}
+
+	h := &Handler{
+		client: &http.Client{},
+		config: config,
+	}
+	for _, raw := range config.Upstreams {
+		u, err := url.Parse(strings.TrimRight(raw, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream URL %q", raw)
+		}
+		h.upstreams = append(h.upstreams, &upstream{
+			baseURL: u,
+			breaker: newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		})
+	}
+	return h, nil
 }
 
-func (h *Handler) CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+// ProxyHandler forwards the request to the ledger backend owning the tenant
+// from X-Tenant-ID.
+func (h *Handler) ProxyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Header.Get(TenantHeader)
+	if tenant == "" {
+		writeError(w, http.StatusBadRequest, ledger.ErrCodeBadRequest, "missing "+TenantHeader+" header")
 		return
 	}
 
-	var req CreateTransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON format")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, ledger.ErrCodeTooLarge, "request body too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, ledger.ErrCodeBadRequest, "failed to read request body")
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	resp, err := h.forward(r, h.route(tenant), body)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid date format, use YYYY-MM-DD")
+		writeUpstreamError(w, err)
 		return
 	}
 
-	tx := &ledger.Transaction{
-		ID:          uuid.New().String(),
-		Amount:      req.Amount,
-		Category:    req.Category,
-		Description: req.Description,
-		Date:        date,
-		Type:        req.Type,
+	copyHeaders(w.Header(), resp.header)
+	if id := ledger.RequestIDFromContext(r.Context()); id != "" {
+		w.Header().Set(ledger.RequestIDHeader, id)
 	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
 
-	if err := h.ledger.AddTransaction(tx); err != nil {
-		switch err {
-		case ledger.ErrBudgetExceeded:
-			writeError(w, http.StatusConflict, "budget exceeded")
-		default:
-			writeError(w, http.StatusBadRequest, err.Error())
-		}
+// StreamHandler forwards a long-lived response, such as the event stream,
+// from the tenant's backend, flushing each chunk as it arrives. Streams are
+// neither retried nor bound by the upstream timeout.
+func (h *Handler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Header.Get(TenantHeader)
+	if tenant == "" {
+		writeError(w, http.StatusBadRequest, ledger.ErrCodeBadRequest, "missing "+TenantHeader+" header")
 		return
 	}
 
-	response := TransactionResponse{
-		ID:          tx.ID,
-		Amount:      tx.Amount,
-		Category:    tx.Category,
-		Description: tx.Description,
-		Date:        tx.Date,
-		Type:        tx.Type,
+	up := h.route(tenant)
+	if !up.breaker.allow() {
+		writeUpstreamError(w, errUpstreamUnavailable)
+		return
 	}
 
-	writeJSON(w, http.StatusCreated, response)
-}
-
-func (h *Handler) ListTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	req, err := upstreamRequest(r.Context(), r, up, nil)
+	if err != nil {
+		writeUpstreamError(w, err)
 		return
 	}
+	req, endSpan := ledger.InjectTraceContext(req)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		endSpan(0, err)
+		up.breaker.failure()
+		writeUpstreamError(w, err)
+		return
+	}
+	defer resp.Body.Close()
+	endSpan(resp.StatusCode, nil)
+	up.breaker.success()
 
-	transactions := h.ledger.ListTransactions()
-	response := make([]TransactionResponse, len(transactions))
+	copyHeaders(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
 
-	for i, tx := range transactions {
-		response[i] = TransactionResponse{
-			ID:          tx.ID,
-			Amount:      tx.Amount,
-			Category:    tx.Category,
-			Description: tx.Description,
-			Date:        tx.Date,
-			Type:        tx.Type,
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+		if err != nil {
+			return
 		}
 	}
+}
 
-	writeJSON(w, http.StatusOK, response)
+// ListTransactionsHandler proxies to the tenant's backend when X-Tenant-ID is
+// set and otherwise merges the lists from every backend. A page given by
+// limit and offset is cut from the lists merged by date, then ID.
+func (h *Handler) ListTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	h.listHandler(w, r)
+}
+
+// ListBudgetsHandler proxies to the tenant's backend. Budgets do not say
+// which tenant they belong to, so lists from several backends are not merged
+// and X-Tenant-ID is required.
+func (h *Handler) ListBudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	h.tenantListHandler(w, r)
+}
+
+func (h *Handler) ListAnomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	h.listHandler(w, r)
+}
+
+// ListGoalsHandler proxies to the tenant's backend, like ListBudgetsHandler.
+func (h *Handler) ListGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	h.tenantListHandler(w, r)
 }
 
-func (h *Handler) CreateBudgetHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+func (h *Handler) tenantListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ledger.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var req CreateBudgetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON format")
+	h.ProxyHandler(w, r)
+}
+
+func (h *Handler) listHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ledger.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	budget := &ledger.Budget{
-		Category: req.Category,
-		Limit:    req.Limit,
+	if r.Header.Get(TenantHeader) != "" {
+		h.ProxyHandler(w, r)
+		return
 	}
 
-	if err := h.ledger.SetBudget(budget); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ledger.ErrCodeBadRequest, err.Error())
 		return
 	}
+	paged := limit > 0 || offset > 0
+	if paged {
+		// Any item of the page may come from any backend, so each is asked
+		// for everything up to the end of the page.
+		query := r.URL.Query()
+		query.Del("offset")
+		if limit > 0 {
+			query.Set("limit", strconv.Itoa(offset+limit))
+		}
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = query.Encode()
+	}
 
-	spent := h.ledger.GetCategorySpending(req.Category)
-	response := BudgetResponse{
-		Category: budget.Category,
-		Limit:    budget.Limit,
-		Spent:    spent,
+	results := make([][]json.RawMessage, len(h.upstreams))
+	errs := make([]error, len(h.upstreams))
+
+	var wg sync.WaitGroup
+	for i, up := range h.upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := h.forward(r, up, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if resp.status != http.StatusOK {
+				errs[i] = fmt.Errorf("%s: status %d", up.baseURL, resp.status)
+				return
+			}
+			if err := json.Unmarshal(resp.body, &results[i]); err != nil {
+				errs[i] = fmt.Errorf("%s: invalid response: %w", up.baseURL, err)
+			}
+		}()
 	}
+	wg.Wait()
 
-	writeJSON(w, http.StatusCreated, response)
-}
+	merged := make([]json.RawMessage, 0)
+	for i := range h.upstreams {
+		if errs[i] != nil {
+			writeUpstreamError(w, errs[i])
+			return
+		}
+		merged = append(merged, results[i]...)
+	}
 
-func (h *Handler) ListBudgetsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
+	if paged {
+		merged = mergeByDate(results)
+		merged = merged[min(offset, len(merged)):]
+		if limit > 0 {
+			merged = merged[:min(limit, len(merged))]
+		}
 	}
+	writeJSON(w, http.StatusOK, merged)
+}
 
-	budgets := h.ledger.ListBudgets()
-	response := make([]BudgetResponse, len(budgets))
+// parsePage reads the limit and offset query parameters, zero when absent.
+func parsePage(query url.Values) (limit, offset int, err error) {
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
 
-	for i, budget := range budgets {
-		spent := h.ledger.GetCategorySpending(budget.Category)
-		response[i] = BudgetResponse{
-			Category: budget.Category,
-			Limit:    budget.Limit,
-			Spent:    spent,
+// mergeByDate merges the backends' lists by date, then ID, taking each
+// list's items in the order it has them. Every item of the first n merged
+// ones is then among the first n of its own list.
+func mergeByDate(lists [][]json.RawMessage) []json.RawMessage {
+	type dated struct {
+		ID   string    `json:"id"`
+		Date time.Time `json:"date"`
+	}
+	heads := make([]dated, len(lists))
+	for i, list := range lists {
+		if len(list) > 0 {
+			json.Unmarshal(list[0], &heads[i])
 		}
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	merged := make([]json.RawMessage, 0)
+	for {
+		next := -1
+		for i, list := range lists {
+			if len(list) == 0 {
+				continue
+			}
+			if next < 0 || heads[i].Date.Before(heads[next].Date) ||
+				(heads[i].Date.Equal(heads[next].Date) && heads[i].ID < heads[next].ID) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return merged
+		}
+
+		merged = append(merged, lists[next][0])
+		lists[next] = lists[next][1:]
+		heads[next] = dated{}
+		if len(lists[next]) > 0 {
+			json.Unmarshal(lists[next][0], &heads[next])
+		}
+	}
 }
 
 func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, http.StatusMethodNotAllowed, ledger.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// RegisterHealthChecks adds a readiness check per upstream that probes its
+// liveness endpoint directly, bypassing the circuit breaker.
+func (h *Handler) RegisterHealthChecks(health *ledger.Health) {
+	for _, up := range h.upstreams {
+		health.Register("upstream "+up.baseURL.String(), func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, up.baseURL.String()+"/health/live", nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := h.client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			io.Copy(io.Discard, resp.Body)
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil
+		})
+	}
+}
+
+// route picks the backend for a tenant by hashing its ID, so a tenant always
+// lands on the same ledger instance.
+func (h *Handler) route(tenant string) *upstream {
+	hash := fnv.New32a()
+	hash.Write([]byte(tenant))
+	return h.upstreams[hash.Sum32()%uint32(len(h.upstreams))]
+}
+
+type upstreamResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// forward sends r to up, retrying GETs and requests carrying an
+// Idempotency-Key on network errors and 502/503/504.
+func (h *Handler) forward(r *http.Request, up *upstream, body []byte) (*upstreamResponse, error) {
+	retryable := r.Method == http.MethodGet || r.Header.Get(ledger.IdempotencyKeyHeader) != ""
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(h.config.RetryBackoff << (attempt - 1)):
+			}
+		}
+
+		if !up.breaker.allow() {
+			return nil, errUpstreamUnavailable
+		}
+
+		resp, err := h.send(r, up, body)
+		if err == nil && !isRetryableStatus(resp.status) {
+			up.breaker.success()
+			return resp, nil
+		}
+		if errors.Is(err, errUpstreamTooLarge) {
+			// The backend answered; retrying would only fetch it again.
+			up.breaker.success()
+			return nil, err
+		}
+
+		up.breaker.failure()
+		if !retryable || attempt >= h.config.MaxRetries || r.Context().Err() != nil {
+			return resp, err
+		}
+	}
+}
+
+func (h *Handler) send(r *http.Request, up *upstream, body []byte) (*upstreamResponse, error) {
+	ctx := r.Context()
+	if h.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := upstreamRequest(ctx, r, up, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req, endSpan := ledger.InjectTraceContext(req)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		endSpan(0, err)
+		return nil, fmt.Errorf("%s: %w", up.baseURL, err)
+	}
+	defer resp.Body.Close()
+	endSpan(resp.StatusCode, nil)
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", up.baseURL, err)
+	}
+	if len(data) > maxUpstreamBody {
+		return nil, fmt.Errorf("%s: %w", up.baseURL, errUpstreamTooLarge)
+	}
+
+	return &upstreamResponse{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+// upstreamRequest builds the request forwarding r to up.
+func upstreamRequest(ctx context.Context, r *http.Request, up *upstream, body io.Reader) (*http.Request, error) {
+	target := *up.baseURL
+	target.Path = up.baseURL.Path + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	copyHeaders(req.Header, r.Header)
+	if id := ledger.RequestIDFromContext(r.Context()); id != "" {
+		req.Header.Set(ledger.RequestIDHeader, id)
+	}
+	if host := clientIP(r); host != "" {
+		req.Header.Add("X-Forwarded-For", host)
+	}
+	return req, nil
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+var hopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Content-Length":      true,
+}
+
+func copyHeaders(dst, src http.Header) {
+	for key, values := range src {
+		if hopHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		for _, v := range values {
+			dst.Add(key, v)
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errUpstreamUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "upstream_unavailable", "upstream unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "upstream_timeout", "upstream timeout")
+	default:
+		writeError(w, http.StatusBadGateway, "upstream_error", "upstream error")
+	}
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -191,6 +530,6 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, ErrorResponse{Error: message})
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ledger.ErrorResponse{Error: message, Code: code})
 }
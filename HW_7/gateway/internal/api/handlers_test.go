@@ -1,289 +1,542 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func TestBudgetHandlers(t *testing.T) {
-	ledgerService := ledger.NewLedger()
-	handler := NewHandler(ledgerService)
+// fakeUpstream runs the real ledger handlers behind an httptest server and
+// records the request IDs it receives.
+type fakeUpstream struct {
+	server     *httptest.Server
+	ledger     *ledger.Ledger
+	requests   atomic.Int32
+	requestIDs chan string
+}
 
-	t.Cleanup(func() {
-		ledgerService.Reset()
-	})
+func newFakeUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
 
-	t.Run("create valid budget", func(t *testing.T) {
-		reqBody := `{"category":"food","limit":5000}`
-		req := httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	f := &fakeUpstream{
+		ledger:     ledger.NewLedger(),
+		requestIDs: make(chan string, 100),
+	}
+	handler := ledger.NewHandler(f.ledger)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	mux.HandleFunc("GET /api/transactions", handler.ListTransactionsHandler)
+	mux.HandleFunc("POST /api/budgets", handler.CreateBudgetHandler)
+	mux.HandleFunc("GET /api/budgets", handler.ListBudgetsHandler)
+	mux.HandleFunc("GET /api/events", handler.EventsHandler)
+
+	traced := ledger.TracingMiddleware(mux)
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		f.requestIDs <- r.Header.Get(ledger.RequestIDHeader)
+		traced.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
 
-		rr := httptest.NewRecorder()
-		handler.CreateBudgetHandler(rr, req)
+func newGateway(t *testing.T, config Config) http.Handler {
+	t.Helper()
 
-		if status := rr.Code; status != http.StatusCreated {
-			t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
-		}
+	handler, err := NewHandler(config)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
 
-		contentType := rr.Header().Get("Content-Type")
-		expectedContentType := "application/json; charset=utf-8"
-		if contentType != expectedContentType {
-			t.Errorf("Expected Content-Type %s, got %s", expectedContentType, contentType)
-		}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/transactions", handler.ListTransactionsHandler)
+	mux.HandleFunc("POST /api/budgets", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/budgets", handler.ListBudgetsHandler)
+	mux.HandleFunc("GET /api/events", handler.StreamHandler)
+	mux.HandleFunc("GET /health", handler.HealthHandler)
+	return ledger.LoggingMiddleware(ledger.TracingMiddleware(mux))
+}
 
-		var response ledger.BudgetResponse
-		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
-			t.Fatalf("Failed to parse response: %v", err)
-		}
+func testConfig(upstreams ...string) Config {
+	config := DefaultConfig()
+	config.Upstreams = upstreams
+	config.RetryBackoff = time.Millisecond
+	config.Timeout = time.Second
+	return config
+}
 
-		if response.Category != "food" {
-			t.Errorf("Expected category 'food', got '%s'", response.Category)
-		}
-		if response.Limit != 5000 {
-			t.Errorf("Expected limit 5000, got %f", response.Limit)
-		}
-	})
+func doRequest(gateway http.Handler, method, path, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set(TenantHeader, tenant)
+	}
+	rr := httptest.NewRecorder()
+	gateway.ServeHTTP(rr, req)
+	return rr
+}
 
-	t.Run("create budget with invalid JSON", func(t *testing.T) {
-		reqBody := `{"category":"food","limit":"invalid"}`
-		req := httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+func TestNewHandler(t *testing.T) {
+	if _, err := NewHandler(DefaultConfig()); err == nil {
+		t.Error("Expected error without upstreams")
+	}
+	if _, err := NewHandler(testConfig("not a url")); err == nil {
+		t.Error("Expected error for invalid upstream URL")
+	}
+}
 
-		rr := httptest.NewRecorder()
-		handler.CreateBudgetHandler(rr, req)
+func TestGateway_Routing(t *testing.T) {
+	first, second := newFakeUpstream(t), newFakeUpstream(t)
+	gateway := newGateway(t, testConfig(first.server.URL, second.server.URL))
 
-		if status := rr.Code; status != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
+	tenants := make([]string, 10)
+	for i := range tenants {
+		tenants[i] = fmt.Sprintf("tenant-%d", i)
+		body := `{"amount":100,"category":"food","date":"2024-01-15","type":"expense"}`
+		rr := doRequest(gateway, "POST", "/api/transactions", tenants[i], body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
 		}
+	}
 
-		var errorResp map[string]string
-		if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
-			t.Fatalf("Failed to parse error response: %v", err)
+	t.Run("tenants are spread across backends", func(t *testing.T) {
+		n1, n2 := len(first.ledger.ListTransactions()), len(second.ledger.ListTransactions())
+		if n1+n2 != 10 || n1 == 0 || n2 == 0 {
+			t.Errorf("Expected 10 transactions split across both backends, got %d and %d", n1, n2)
 		}
+	})
 
-		if _, exists := errorResp["error"]; !exists {
-			t.Error("Expected error field in response")
+	t.Run("tenant is sticky", func(t *testing.T) {
+		for _, tenant := range tenants {
+			a := doRequest(gateway, "GET", "/api/transactions", tenant, "")
+			b := doRequest(gateway, "GET", "/api/transactions", tenant, "")
+			if a.Body.String() != b.Body.String() {
+				t.Errorf("Expected tenant %s to hit the same backend", tenant)
+			}
 		}
 	})
 
-	t.Run("create budget with negative limit", func(t *testing.T) {
-		reqBody := `{"category":"food","limit":-100}`
-		req := httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	t.Run("missing tenant on write", func(t *testing.T) {
+		rr := doRequest(gateway, "POST", "/api/budgets", "", `{"category":"food","limit":10}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
 
-		rr := httptest.NewRecorder()
-		handler.CreateBudgetHandler(rr, req)
+	t.Run("aggregated list", func(t *testing.T) {
+		rr := doRequest(gateway, "GET", "/api/transactions", "", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
 
-		if status := rr.Code; status != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
+		var transactions []ledger.TransactionResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &transactions); err != nil {
+			t.Fatalf("Failed to parse transactions list: %v", err)
+		}
+		if len(transactions) != 10 {
+			t.Errorf("Expected 10 transactions from both backends, got %d", len(transactions))
 		}
 	})
 
-	t.Run("list budgets", func(t *testing.T) {
-		createReqBody := `{"category":"entertainment","limit":3000}`
-		createReq := httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(createReqBody))
-		createReq.Header.Set("Content-Type", "application/json")
-		createRR := httptest.NewRecorder()
-		handler.CreateBudgetHandler(createRR, createReq)
+	t.Run("budget lists are per tenant", func(t *testing.T) {
+		if rr := doRequest(gateway, "GET", "/api/budgets", "", ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d without a tenant, got %d", http.StatusBadRequest, rr.Code)
+		}
 
-		listReq := httptest.NewRequest("GET", "/api/budgets", nil)
-		listRR := httptest.NewRecorder()
-		handler.ListBudgetsHandler(listRR, listReq)
+		for _, category := range []string{"rent", "food", "travel"} {
+			doRequest(gateway, "POST", "/api/budgets", "tenant-1", `{"category":"`+category+`","limit":1000}`)
+		}
+		rr := doRequest(gateway, "GET", "/api/budgets", "tenant-1", "")
+		var budgets []ledger.BudgetResponse
+		json.Unmarshal(rr.Body.Bytes(), &budgets)
+		categories := make([]string, len(budgets))
+		for i, budget := range budgets {
+			categories[i] = budget.Category
+		}
+		if got := strings.Join(categories, ","); got != "food,rent,travel" {
+			t.Errorf("Expected the tenant's budgets by category, got %q", got)
+		}
+	})
 
-		if status := listRR.Code; status != http.StatusOK {
-			t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	t.Run("request body limit", func(t *testing.T) {
+		body := `{"description":"` + strings.Repeat("x", maxRequestBody) + `"}`
+		rr := doRequest(gateway, "POST", "/api/transactions", "tenant-0", body)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
 		}
+	})
 
-		var budgets []ledger.BudgetResponse
-		if err := json.Unmarshal(listRR.Body.Bytes(), &budgets); err != nil {
-			t.Fatalf("Failed to parse budgets list: %v", err)
+	t.Run("upstream errors pass through", func(t *testing.T) {
+		rr := doRequest(gateway, "POST", "/api/budgets", "tenant-0", `{"category":"food","limit":-1}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
 		}
 
-		if len(budgets) != 2 {
-			t.Errorf("Expected 2 budgets, got %d", len(budgets))
+		var errorResp ledger.ErrorResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil || errorResp.Error == "" {
+			t.Errorf("Expected upstream error body, got %s", rr.Body.String())
 		}
 	})
 }
 
-func TestTransactionHandlers(t *testing.T) {
-	ledgerService := ledger.NewLedger()
-	handler := NewHandler(ledgerService)
+func TestGateway_PagedList(t *testing.T) {
+	first, second := newFakeUpstream(t), newFakeUpstream(t)
+	gateway := newGateway(t, testConfig(first.server.URL, second.server.URL))
 
-	t.Cleanup(func() {
-		ledgerService.Reset()
-	})
+	// Days alternate between the backends, so every page spans both.
+	for day := 1; day <= 8; day++ {
+		backend := first
+		if day%2 == 0 {
+			backend = second
+		}
+		backend.ledger.AddTransaction(&ledger.Transaction{
+			ID:       fmt.Sprintf("day-%d", day),
+			Amount:   10,
+			Category: "food",
+			Date:     time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Type:     "expense",
+		})
+	}
 
-	t.Run("setup budget", func(t *testing.T) {
-		reqBody := `{"category":"food","limit":5000}`
-		req := httptest.NewRequest("POST", "/api/budgets", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	tests := []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"?limit=3&offset=1", http.StatusOK, "day-2,day-3,day-4"},
+		{"?offset=6", http.StatusOK, "day-7,day-8"},
+		{"?limit=2&offset=10", http.StatusOK, ""},
+		{"?limit=-1", http.StatusBadRequest, ""},
+	}
 
-		rr := httptest.NewRecorder()
-		handler.CreateBudgetHandler(rr, req)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := doRequest(gateway, "GET", "/api/transactions"+tt.query, "", "")
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
 
-		if rr.Code != http.StatusCreated {
-			t.Fatalf("Failed to setup budget: %d", rr.Code)
-		}
-	})
+			var transactions []ledger.TransactionResponse
+			json.Unmarshal(rr.Body.Bytes(), &transactions)
+			ids := make([]string, len(transactions))
+			for i, tx := range transactions {
+				ids[i] = tx.ID
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
 
-	t.Run("create valid transaction", func(t *testing.T) {
-		reqBody := `{
-			"amount": 1000,
-			"category": "food",
-			"description": "groceries",
-			"date": "2024-01-15",
-			"type": "expense"
-		}`
+func TestGateway_UpstreamBodyLimit(t *testing.T) {
+	var calls atomic.Int32
+	huge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write(bytes.Repeat([]byte(" "), maxUpstreamBody+1))
+	}))
+	t.Cleanup(huge.Close)
+	gateway := newGateway(t, testConfig(huge.URL))
+
+	rr := doRequest(gateway, "GET", "/api/budgets", "alice", "")
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected status %d, got %d", http.StatusBadGateway, rr.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls.Load())
+	}
+}
 
-		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+func TestGateway_RequestID(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	gateway := newGateway(t, testConfig(upstream.server.URL))
 
+	t.Run("propagated", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/budgets", nil)
+		req.Header.Set(TenantHeader, "alice")
+		req.Header.Set(ledger.RequestIDHeader, "req-123")
 		rr := httptest.NewRecorder()
-		handler.CreateTransactionHandler(rr, req)
+		gateway.ServeHTTP(rr, req)
 
-		if status := rr.Code; status != http.StatusCreated {
-			t.Errorf("Expected status %d, got %d", http.StatusCreated, status)
+		if got := <-upstream.requestIDs; got != "req-123" {
+			t.Errorf("Expected upstream to receive req-123, got %q", got)
 		}
-
-		var response ledger.TransactionResponse
-		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
-			t.Fatalf("Failed to parse response: %v", err)
+		if got := rr.Header().Values(ledger.RequestIDHeader); len(got) != 1 || got[0] != "req-123" {
+			t.Errorf("Expected response request ID req-123, got %v", got)
 		}
+	})
 
-		if response.Amount != 1000 {
-			t.Errorf("Expected amount 1000, got %f", response.Amount)
-		}
-		if response.Category != "food" {
-			t.Errorf("Expected category 'food', got '%s'", response.Category)
+	t.Run("generated", func(t *testing.T) {
+		rr := doRequest(gateway, "GET", "/api/budgets", "alice", "")
+
+		id := rr.Header().Get(ledger.RequestIDHeader)
+		if id == "" {
+			t.Fatal("Expected generated request ID")
 		}
-		if response.Description != "groceries" {
-			t.Errorf("Expected description 'groceries', got '%s'", response.Description)
+		if got := <-upstream.requestIDs; got != id {
+			t.Errorf("Expected upstream to receive %q, got %q", id, got)
 		}
 	})
+}
 
-	t.Run("transaction exceeds budget", func(t *testing.T) {
-		reqBody := `{
-			"amount": 4500,
-			"category": "food",
-			"description": "expensive dinner",
-			"date": "2024-01-16",
-			"type": "expense"
-		}`
+func TestGateway_TracePropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
 
-		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	upstream := newFakeUpstream(t)
+	gateway := newGateway(t, testConfig(upstream.server.URL))
 
-		rr := httptest.NewRecorder()
-		handler.CreateTransactionHandler(rr, req)
+	body := `{"amount":100,"category":"food","date":"2024-01-15","type":"expense"}`
+	if rr := doRequest(gateway, "POST", "/api/transactions", "alice", body); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
 
-		if status := rr.Code; status != http.StatusConflict {
-			t.Errorf("Expected status %d, got %d", http.StatusConflict, status)
+	var gatewaySpan, clientSpan, ledgerSpan, addSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.Name() == "Ledger.AddTransaction":
+			addSpan = span
+		case span.SpanKind() == trace.SpanKindClient:
+			clientSpan = span
+		case span.SpanKind() == trace.SpanKindServer && span.Parent().IsRemote():
+			ledgerSpan = span
+		case span.SpanKind() == trace.SpanKindServer:
+			gatewaySpan = span
 		}
+	}
+	if gatewaySpan == nil || clientSpan == nil || ledgerSpan == nil || addSpan == nil {
+		t.Fatalf("Expected gateway, client, ledger and AddTransaction spans, got %v", recorder.Ended())
+	}
 
-		var errorResp map[string]string
-		if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
-			t.Fatalf("Failed to parse error response: %v", err)
+	traceID := gatewaySpan.SpanContext().TraceID()
+	for _, span := range []sdktrace.ReadOnlySpan{clientSpan, ledgerSpan, addSpan} {
+		if span.SpanContext().TraceID() != traceID {
+			t.Errorf("Expected span %s to share the gateway trace ID", span.Name())
 		}
+	}
+	if clientSpan.Parent().SpanID() != gatewaySpan.SpanContext().SpanID() {
+		t.Error("Expected upstream call to be a child of the gateway span")
+	}
+	if ledgerSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("Expected ledger span to continue the traceparent sent by the gateway")
+	}
+	if addSpan.Parent().SpanID() != ledgerSpan.SpanContext().SpanID() {
+		t.Error("Expected AddTransaction span to be a child of the ledger span")
+	}
+}
 
-		if errorResp["error"] != "budget exceeded" {
-			t.Errorf("Expected error 'budget exceeded', got '%s'", errorResp["error"])
+func TestGateway_Retries(t *testing.T) {
+	var calls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
 		}
-	})
+		writeJSON(w, http.StatusOK, []ledger.BudgetResponse{})
+	}))
+	t.Cleanup(flaky.Close)
 
-	t.Run("list transactions", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/api/transactions", nil)
-		rr := httptest.NewRecorder()
-		handler.ListTransactionsHandler(rr, req)
+	gateway := newGateway(t, testConfig(flaky.URL))
 
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	t.Run("idempotent request is retried", func(t *testing.T) {
+		rr := doRequest(gateway, "GET", "/api/budgets", "alice", "")
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
 		}
-
-		var transactions []ledger.TransactionResponse
-		if err := json.Unmarshal(rr.Body.Bytes(), &transactions); err != nil {
-			t.Fatalf("Failed to parse transactions list: %v", err)
+		if calls.Load() != 3 {
+			t.Errorf("Expected 3 upstream calls, got %d", calls.Load())
 		}
+	})
 
-		if len(transactions) != 1 {
-			t.Errorf("Expected 1 transaction, got %d", len(transactions))
+	t.Run("non-idempotent request is not retried", func(t *testing.T) {
+		calls.Store(0)
+		rr := doRequest(gateway, "POST", "/api/budgets", "alice", `{"category":"food","limit":10}`)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected 1 upstream call, got %d", calls.Load())
 		}
 	})
+}
 
-	t.Run("invalid transaction JSON", func(t *testing.T) {
-		reqBody := `{
-			"amount": "invalid",
-			"category": "food",
-			"date": "2024-01-15",
-			"type": "expense"
-		}`
+func TestGateway_Timeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
 
-		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	config := testConfig(slow.URL)
+	config.Timeout = 20 * time.Millisecond
+	config.MaxRetries = 0
+	gateway := newGateway(t, config)
 
-		rr := httptest.NewRecorder()
-		handler.CreateTransactionHandler(rr, req)
+	rr := doRequest(gateway, "GET", "/api/budgets", "alice", "")
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusGatewayTimeout, rr.Code)
+	}
+}
 
-		if status := rr.Code; status != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
-		}
-	})
+func TestGateway_EventStream(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	config := testConfig(upstream.server.URL)
+	config.Timeout = 20 * time.Millisecond
+	gateway := httptest.NewServer(newGateway(t, config))
+	t.Cleanup(gateway.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", gateway.URL+"/api/events", nil)
+	req.Header.Set(TenantHeader, "alice")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
 
-	t.Run("transaction with invalid date format", func(t *testing.T) {
-		reqBody := `{
-			"amount": 1000,
-			"category": "food",
-			"date": "15-01-2024",
-			"type": "expense"
-		}`
+	// The stream must outlive the gateway's upstream timeout.
+	time.Sleep(2 * config.Timeout)
+	upstream.ledger.SetBudget(&ledger.Budget{Category: "food", Limit: 100})
 
-		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(reqBody))
-		req.Header.Set("Content-Type", "application/json")
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() || scanner.Text() != "id: 1" || !scanner.Scan() || scanner.Text() != "event: budget.changed" {
+		t.Errorf("Expected the budget event, got %q (%v)", scanner.Text(), scanner.Err())
+	}
+}
 
-		rr := httptest.NewRecorder()
-		handler.CreateTransactionHandler(rr, req)
+func TestGateway_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+
+	config := testConfig(down.URL)
+	config.MaxRetries = 0
+	config.BreakerThreshold = 3
+	config.BreakerCooldown = time.Hour
+	gateway := newGateway(t, config)
+
+	for i := 0; i < 3; i++ {
+		doRequest(gateway, "GET", "/api/budgets", "alice", "")
+	}
+
+	rr := doRequest(gateway, "GET", "/api/budgets", "alice", "")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected open circuit to stop upstream calls at 3, got %d", calls.Load())
+	}
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	b.failure()
+	if b.allow() {
+		t.Fatal("Expected breaker to be open")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("Expected a trial call after cooldown")
+	}
+	if b.allow() {
+		t.Error("Expected only one trial call while half-open")
+	}
+
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Error("Expected breaker to close after a successful trial")
+	}
+}
 
-		if status := rr.Code; status != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
+func TestGateway_ReadinessChecksUpstreams(t *testing.T) {
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health/live" {
+			w.WriteHeader(http.StatusNotFound)
+			return
 		}
-	})
+		writeJSON(w, http.StatusOK, ledger.HealthResponse{Status: ledger.HealthOK})
+	}))
+	t.Cleanup(live.Close)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	handler, err := NewHandler(testConfig(live.URL, down.URL))
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	health := ledger.NewHealth()
+	handler.RegisterHealthChecks(health)
+	health.SetReady()
+
+	response := health.Check(context.Background())
+	if response.Status != ledger.HealthFailed {
+		t.Errorf("Expected %s with one upstream down, got %s", ledger.HealthFailed, response.Status)
+	}
+	if len(response.Checks) != 2 {
+		t.Fatalf("Expected a check per upstream, got %d", len(response.Checks))
+	}
+	if got := response.Checks[0]; got.Name != "upstream "+live.URL || got.Status != ledger.HealthOK {
+		t.Errorf("Expected live upstream to pass, got %+v", got)
+	}
+	if got := response.Checks[1]; got.Name != "upstream "+down.URL || got.Status != ledger.HealthFailed {
+		t.Errorf("Expected stopped upstream to fail, got %+v", got)
+	}
 }
 
 func TestMethodNotAllowed(t *testing.T) {
-	ledgerService := ledger.NewLedger()
-	handler := NewHandler(ledgerService)
-
-	tests := []struct {
-		method string
-		path   string
-	}{
-		{"PUT", "/api/transactions"},
-		{"DELETE", "/api/transactions"},
-		{"PUT", "/api/budgets"},
-		{"DELETE", "/api/budgets"},
+	handler, err := NewHandler(testConfig("http://localhost:1"))
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
 	}
 
-	for _, tt := range tests {
-		t.Run(fmt.Sprintf("%s %s", tt.method, tt.path), func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, tt.path, nil)
+	for _, method := range []string{"PUT", "DELETE"} {
+		t.Run(method, func(t *testing.T) {
 			rr := httptest.NewRecorder()
-
-			switch tt.path {
-			case "/api/transactions":
-				handler.CreateTransactionHandler(rr, req)
-			case "/api/budgets":
-				handler.CreateBudgetHandler(rr, req)
-			}
+			handler.ListBudgetsHandler(rr, httptest.NewRequest(method, "/api/budgets", nil))
 
 			if status := rr.Code; status != http.StatusMethodNotAllowed {
-				t.Errorf("Expected status %d for %s %s, got %d",
-					http.StatusMethodNotAllowed, tt.method, tt.path, status)
+				t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, status)
 			}
 		})
 	}
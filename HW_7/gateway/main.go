@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/gateway/internal/api"
+	"github.com/jukov801/Golang_MIPT/HW_6/ledger"
+)
+
+func main() {
+	config := api.DefaultConfig()
+
+	port := flag.String("addr", ":8000", "address to listen on")
+	upstreams := flag.String("upstreams", "http://localhost:8080", "comma-separated ledger service URLs")
+	flag.DurationVar(&config.Timeout, "timeout", config.Timeout, "per-attempt upstream timeout")
+	flag.IntVar(&config.MaxRetries, "retries", config.MaxRetries, "retries for idempotent requests")
+	flag.IntVar(&config.BreakerThreshold, "breaker-threshold", config.BreakerThreshold, "consecutive failures that open the circuit")
+	flag.DurationVar(&config.BreakerCooldown, "breaker-cooldown", config.BreakerCooldown, "how long an open circuit rejects calls")
+	logFormat := flag.String("log-format", "json", "log output format: json or text")
+	traceExporter := flag.String("trace-exporter", "none", "trace exporter: none, stdout or otlp")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 20*time.Second, "how long shutdown waits for in-flight requests")
+	flag.Parse()
+
+	slog.SetDefault(ledger.NewLogger(os.Stdout, *logFormat, slog.LevelInfo))
+
+	shutdownTracing, err := ledger.SetupTracing(context.Background(), *traceExporter, "gateway", os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	config.Upstreams = strings.Split(*upstreams, ",")
+
+	handler, err := api.NewHandler(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/transactions", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/transactions:batch", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/transactions", handler.ListTransactionsHandler)
+	mux.HandleFunc("GET /api/anomalies", handler.ListAnomaliesHandler)
+	mux.HandleFunc("GET /api/events", handler.StreamHandler)
+
+	mux.HandleFunc("POST /api/transactions/{id}/attachments", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments/{attachment}", handler.ProxyHandler)
+	mux.HandleFunc("DELETE /api/transactions/{id}/attachments/{attachment}", handler.ProxyHandler)
+
+	mux.HandleFunc("POST /api/budgets", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/budgets", handler.ListBudgetsHandler)
+	mux.HandleFunc("GET /api/budgets/{category}/forecast", handler.ProxyHandler)
+
+	mux.HandleFunc("POST /api/goals", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/goals", handler.ListGoalsHandler)
+	mux.HandleFunc("GET /api/goals/{id}", handler.ProxyHandler)
+	mux.HandleFunc("PUT /api/goals/{id}", handler.ProxyHandler)
+	mux.HandleFunc("DELETE /api/goals/{id}", handler.ProxyHandler)
+
+	mux.HandleFunc("GET /api/summary", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/reports/tags", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/balances", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/balances/settlements", handler.ProxyHandler)
+
+	mux.HandleFunc("PUT /api/transactions/{id}/status", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/reconciliations", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/reconciliations", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/reconciliations/{id}", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/matches", handler.ProxyHandler)
+	mux.HandleFunc("DELETE /api/reconciliations/{id}/matches/{line}", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/complete", handler.ProxyHandler)
+
+	mux.HandleFunc("GET /api/periods", handler.ProxyHandler)
+	mux.HandleFunc("GET /api/periods/audit", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/periods/{period}/close", handler.ProxyHandler)
+	mux.HandleFunc("POST /api/periods/{period}/reopen", handler.ProxyHandler)
+
+	health := ledger.NewHealth()
+	handler.RegisterHealthChecks(health)
+
+	mux.HandleFunc("GET /health", handler.HealthHandler)
+	mux.HandleFunc("GET /health/live", health.LiveHandler)
+	mux.HandleFunc("GET /health/ready", health.ReadyHandler)
+
+	server := &http.Server{
+		Addr:              *port,
+		Handler:           ledger.LoggingMiddleware(ledger.TracingMiddleware(mux)),
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	fmt.Printf("Gateway starting on http://localhost%s\n", *port)
+	fmt.Printf("Upstreams: %s\n", strings.Join(config.Upstreams, ", "))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", *port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	health.SetReady()
+
+	<-ctx.Done()
+	health.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown failed", "error", err)
+	}
+}
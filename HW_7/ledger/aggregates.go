@@ -0,0 +1,166 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const periodLayout = "2006-01"
+
+// spendingEpsilon is the relative float drift tolerated between incremental
+// and recomputed sums.
+const spendingEpsilon = 1e-9
+
+// spendingIndex books posted expenses by category and period, and pending
+// ones by category only.
+type spendingIndex struct {
+	totals  map[string]float64
+	periods map[string]map[string]float64
+	pending map[string]float64
+}
+
+func newSpendingIndex() *spendingIndex {
+	return &spendingIndex{
+		totals:  make(map[string]float64),
+		periods: make(map[string]map[string]float64),
+		pending: make(map[string]float64),
+	}
+}
+
+func periodKey(date time.Time) string {
+	return date.Format(periodLayout)
+}
+
+func (s *spendingIndex) add(tx *Transaction) {
+	s.apply(tx, 1)
+}
+
+func (s *spendingIndex) remove(tx *Transaction) {
+	s.apply(tx, -1)
+}
+
+// apply books each line of an expense into its category, adding it for sign 1
+// and removing it for -1.
+func (s *spendingIndex) apply(tx *Transaction, sign float64) {
+	if tx.Type != "expense" {
+		return
+	}
+
+	for _, line := range tx.Lines() {
+		delta := sign * line.Amount
+		if tx.Status == StatusPending {
+			s.pending[line.Category] += delta
+			continue
+		}
+		s.totals[line.Category] += delta
+
+		byPeriod, exists := s.periods[line.Category]
+		if !exists {
+			byPeriod = make(map[string]float64)
+			s.periods[line.Category] = byPeriod
+		}
+		byPeriod[periodKey(tx.Date)] += delta
+	}
+}
+
+func (s *spendingIndex) total(category string) float64 {
+	return s.totals[category]
+}
+
+// committed is what pending expenses will add to the category.
+func (s *spendingIndex) committed(category string) float64 {
+	return s.pending[category]
+}
+
+// used is the category's posted and pending spending; zero for a nil index.
+func (s *spendingIndex) used(category string) float64 {
+	if s == nil {
+		return 0
+	}
+	return s.totals[category] + s.pending[category]
+}
+
+func (s *spendingIndex) period(category string, date time.Time) float64 {
+	return s.periods[category][periodKey(date)]
+}
+
+func rebuildSpendingIndex(transactions []*Transaction) *spendingIndex {
+	s := newSpendingIndex()
+	for _, tx := range transactions {
+		s.add(tx)
+	}
+	return s
+}
+
+// CheckConsistency recomputes spending from scratch and reports every
+// category or period where the maintained aggregates disagree with it.
+func (l *Ledger) CheckConsistency() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expected := rebuildSpendingIndex(l.Transactions)
+
+	var diffs []string
+	for _, category := range unionKeys(expected.totals, l.spending.totals) {
+		want, got := expected.totals[category], l.spending.totals[category]
+		if !sameAmount(want, got) {
+			diffs = append(diffs, fmt.Sprintf("%s: spent %.2f, aggregate %.2f", category, want, got))
+		}
+	}
+
+	for _, category := range unionKeys(expected.pending, l.spending.pending) {
+		want, got := expected.pending[category], l.spending.pending[category]
+		if !sameAmount(want, got) {
+			diffs = append(diffs, fmt.Sprintf("%s: committed %.2f, aggregate %.2f", category, want, got))
+		}
+	}
+
+	for category, wantPeriods := range expected.periods {
+		diffs = append(diffs, diffPeriods(category, wantPeriods, l.spending.periods[category])...)
+	}
+	for category, gotPeriods := range l.spending.periods {
+		if _, exists := expected.periods[category]; !exists {
+			diffs = append(diffs, diffPeriods(category, nil, gotPeriods)...)
+		}
+	}
+
+	if len(diffs) > 0 {
+		sort.Strings(diffs)
+		return fmt.Errorf("spending aggregates out of sync: %s", strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+func diffPeriods(category string, want, got map[string]float64) []string {
+	var diffs []string
+	for _, period := range unionKeys(want, got) {
+		if !sameAmount(want[period], got[period]) {
+			diffs = append(diffs, fmt.Sprintf("%s/%s: spent %.2f, aggregate %.2f", category, period, want[period], got[period]))
+		}
+	}
+	return diffs
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) <= spendingEpsilon*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func unionKeys(a, b map[string]float64) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		seen[k] = struct{}{}
+	}
+	for k := range b {
+		seen[k] = struct{}{}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,208 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AnomalyOutlier     = "outlier"
+	AnomalyDuplicate   = "duplicate"
+	AnomalyNewCategory = "new_category"
+)
+
+type Anomaly struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+type AnomalyQuery struct {
+	Kind string `query:"kind" enum:"outlier,duplicate,new_category"`
+}
+
+// AnomalyDetector flags unusual transactions. The ledger calls Detect for each
+// new transaction before storing it, and Observe/Forget as transactions are
+// stored and removed, so detectors can keep incremental state instead of
+// rescanning the history.
+type AnomalyDetector interface {
+	Detect(tx *Transaction) []Anomaly
+	Observe(tx *Transaction)
+	Forget(tx *Transaction)
+}
+
+func (l *Ledger) SetAnomalyDetector(detector AnomalyDetector) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.detector = detector
+	for _, tx := range l.Transactions {
+		detector.Observe(tx)
+	}
+}
+
+// ListAnomalies returns flagged transactions, optionally only those with an
+// anomaly of the given kind.
+func (l *Ledger) ListAnomalies(kind string) []*Transaction {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	flagged := make([]*Transaction, 0)
+	for _, tx := range l.Transactions {
+		for _, a := range tx.Anomalies {
+			if kind == "" || a.Kind == kind {
+				flagged = append(flagged, tx)
+				break
+			}
+		}
+	}
+	return flagged
+}
+
+// StatisticalDetector flags expenses whose log-amount lies more than ZScore
+// standard deviations above the category mean, charges repeating the same
+// amount and description within DuplicateWindow, and the first transaction of
+// a category.
+type StatisticalDetector struct {
+	ZScore          float64
+	MinSamples      int
+	DuplicateWindow time.Duration
+
+	mu         sync.Mutex
+	stats      map[string]*runningStats
+	seen       map[string]int
+	duplicates map[string][]time.Time
+}
+
+func NewStatisticalDetector() *StatisticalDetector {
+	return &StatisticalDetector{
+		ZScore:          3,
+		MinSamples:      5,
+		DuplicateWindow: 3 * 24 * time.Hour,
+		stats:           make(map[string]*runningStats),
+		seen:            make(map[string]int),
+		duplicates:      make(map[string][]time.Time),
+	}
+}
+
+func (d *StatisticalDetector) Detect(tx *Transaction) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var anomalies []Anomaly
+
+	if d.seen[categoryKey(tx)] == 0 {
+		anomalies = append(anomalies, Anomaly{
+			Kind:   AnomalyNewCategory,
+			Detail: fmt.Sprintf("first %s in category %q", tx.Type, tx.Category),
+		})
+	}
+
+	if stats := d.stats[tx.Category]; tx.Type == "expense" && stats != nil && stats.n >= d.MinSamples {
+		if sd := stats.stddev(); sd > 0 {
+			if z := (math.Log(tx.Amount) - stats.mean) / sd; z > d.ZScore {
+				anomalies = append(anomalies, Anomaly{
+					Kind:   AnomalyOutlier,
+					Detail: fmt.Sprintf("amount is %.1f standard deviations above the usual %s spend", z, tx.Category),
+				})
+			}
+		}
+	}
+
+	for _, date := range d.duplicates[duplicateKey(tx)] {
+		if gap := tx.Date.Sub(date).Abs(); gap <= d.DuplicateWindow {
+			anomalies = append(anomalies, Anomaly{
+				Kind:   AnomalyDuplicate,
+				Detail: fmt.Sprintf("same charge recorded on %s", date.Format(dateLayout)),
+			})
+			break
+		}
+	}
+
+	return anomalies
+}
+
+func (d *StatisticalDetector) Observe(tx *Transaction) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seen[categoryKey(tx)]++
+	if tx.Type == "expense" {
+		stats, exists := d.stats[tx.Category]
+		if !exists {
+			stats = &runningStats{}
+			d.stats[tx.Category] = stats
+		}
+		stats.add(math.Log(tx.Amount))
+	}
+
+	key := duplicateKey(tx)
+	d.duplicates[key] = append(d.duplicates[key], tx.Date)
+}
+
+func (d *StatisticalDetector) Forget(tx *Transaction) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seen[categoryKey(tx)]--
+	if stats, exists := d.stats[tx.Category]; exists && tx.Type == "expense" {
+		stats.remove(math.Log(tx.Amount))
+	}
+
+	key := duplicateKey(tx)
+	dates := d.duplicates[key]
+	for i, date := range dates {
+		if date.Equal(tx.Date) {
+			d.duplicates[key] = append(dates[:i], dates[i+1:]...)
+			break
+		}
+	}
+	if len(d.duplicates[key]) == 0 {
+		delete(d.duplicates, key)
+	}
+}
+
+func categoryKey(tx *Transaction) string {
+	return tx.Type + "|" + tx.Category
+}
+
+func duplicateKey(tx *Transaction) string {
+	return categoryKey(tx) + "|" + strconv.FormatFloat(tx.Amount, 'f', 2, 64) + "|" +
+		strings.ToLower(strings.TrimSpace(tx.Description))
+}
+
+// runningStats keeps a mean and variance with Welford's algorithm, which also
+// supports removing samples.
+type runningStats struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func (s *runningStats) add(x float64) {
+	s.n++
+	delta := x - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (x - s.mean)
+}
+
+func (s *runningStats) remove(x float64) {
+	if s.n <= 1 {
+		*s = runningStats{}
+		return
+	}
+	delta := x - s.mean
+	s.mean -= delta / float64(s.n-1)
+	s.m2 -= delta * (x - s.mean)
+	s.n--
+}
+
+func (s *runningStats) stddev() float64 {
+	if s.n < 2 {
+		return 0
+	}
+	return math.Sqrt(math.Max(0, s.m2) / float64(s.n-1))
+}
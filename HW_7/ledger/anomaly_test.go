@@ -0,0 +1,187 @@
+package ledger
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func kinds(anomalies []Anomaly) map[string]bool {
+	set := make(map[string]bool)
+	for _, a := range anomalies {
+		set[a.Kind] = true
+	}
+	return set
+}
+
+// syntheticDetector observes n groceries expenses drawn around 50 with a few
+// units of noise, one per day from start.
+func syntheticDetector(n int, start time.Time) *StatisticalDetector {
+	d := NewStatisticalDetector()
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		d.Observe(&Transaction{
+			Amount:   50 + rng.NormFloat64()*5,
+			Category: "groceries",
+			Date:     start.AddDate(0, 0, i),
+			Type:     "expense",
+		})
+	}
+	return d
+}
+
+func TestStatisticalDetector(t *testing.T) {
+	start := date("2024-01-01")
+	d := syntheticDetector(60, start)
+	d.Observe(&Transaction{Amount: 12.99, Category: "subscriptions", Description: "Music", Date: date("2024-02-01"), Type: "expense"})
+
+	tests := []struct {
+		name string
+		tx   *Transaction
+		want []string
+	}{
+		{
+			name: "typical expense",
+			tx:   &Transaction{Amount: 55, Category: "groceries", Date: date("2024-03-10"), Type: "expense"},
+		},
+		{
+			name: "far above distribution",
+			tx:   &Transaction{Amount: 400, Category: "groceries", Date: date("2024-03-10"), Type: "expense"},
+			want: []string{AnomalyOutlier},
+		},
+		{
+			name: "below distribution is not flagged",
+			tx:   &Transaction{Amount: 5, Category: "groceries", Date: date("2024-03-10"), Type: "expense"},
+		},
+		{
+			name: "duplicate within window",
+			tx:   &Transaction{Amount: 12.99, Category: "subscriptions", Description: " music", Date: date("2024-02-03"), Type: "expense"},
+			want: []string{AnomalyDuplicate},
+		},
+		{
+			name: "same charge outside window",
+			tx:   &Transaction{Amount: 12.99, Category: "subscriptions", Description: "Music", Date: date("2024-03-01"), Type: "expense"},
+		},
+		{
+			name: "first-ever category",
+			tx:   &Transaction{Amount: 30, Category: "pets", Date: date("2024-03-10"), Type: "expense"},
+			want: []string{AnomalyNewCategory},
+		},
+		{
+			name: "first income in an expense category",
+			tx:   &Transaction{Amount: 30, Category: "groceries", Date: date("2024-03-10"), Type: "income"},
+			want: []string{AnomalyNewCategory},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := kinds(d.Detect(tt.tx))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for _, kind := range tt.want {
+				if !got[kind] {
+					t.Errorf("Expected %s, got %v", kind, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStatisticalDetector_MinSamples(t *testing.T) {
+	d := syntheticDetector(4, date("2024-01-01"))
+
+	tx := &Transaction{Amount: 400, Category: "groceries", Date: date("2024-03-10"), Type: "expense"}
+	if got := kinds(d.Detect(tx)); got[AnomalyOutlier] {
+		t.Error("Expected no outlier before MinSamples observations")
+	}
+}
+
+func TestStatisticalDetector_Forget(t *testing.T) {
+	d := NewStatisticalDetector()
+	first := &Transaction{Amount: 20, Category: "books", Description: "Novel", Date: date("2024-01-10"), Type: "expense"}
+	d.Observe(first)
+	d.Forget(first)
+
+	again := &Transaction{Amount: 20, Category: "books", Description: "Novel", Date: date("2024-01-11"), Type: "expense"}
+	got := kinds(d.Detect(again))
+	if got[AnomalyDuplicate] || !got[AnomalyNewCategory] {
+		t.Errorf("Expected forgotten transaction to leave no trace, got %v", got)
+	}
+}
+
+func TestRunningStats_Remove(t *testing.T) {
+	var all, partial runningStats
+	for _, x := range []float64{3, 7, 1, 9, 4} {
+		all.add(x)
+	}
+	for _, x := range []float64{3, 1, 4} {
+		partial.add(x)
+	}
+	all.remove(7)
+	all.remove(9)
+
+	if all.n != partial.n || !approx(all.mean, partial.mean) || !approx(all.stddev(), partial.stddev()) {
+		t.Errorf("Expected %+v after removal, got %+v", partial, all)
+	}
+}
+
+func TestLedger_Anomalies(t *testing.T) {
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+
+	for i := 0; i < 10; i++ {
+		ledger.AddTransaction(&Transaction{ID: strconv.Itoa(i), Amount: 50 + float64(i), Category: "food", Date: date("2024-01-01").AddDate(0, 0, i), Type: "expense"})
+	}
+	big := &Transaction{ID: "big", Amount: 900, Category: "food", Date: date("2024-01-20"), Type: "expense"}
+	if err := ledger.AddTransaction(big); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !kinds(big.Anomalies)[AnomalyOutlier] {
+		t.Fatalf("Expected outlier flag on transaction, got %v", big.Anomalies)
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		ids    []string
+	}{
+		{"", http.StatusOK, []string{"0", "big"}},
+		{"?kind=outlier", http.StatusOK, []string{"big"}},
+		{"?kind=new_category", http.StatusOK, []string{"0"}},
+		{"?kind=duplicate", http.StatusOK, []string{}},
+		{"?kind=weird", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ListAnomaliesHandler(rr, httptest.NewRequest("GET", "/api/anomalies"+tt.query, nil))
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response []TransactionResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(response) != len(tt.ids) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.ids), len(response))
+			}
+			for i, id := range tt.ids {
+				if response[i].ID != id || len(response[i].Anomalies) == 0 {
+					t.Errorf("Expected flagged transaction %s, got %+v", id, response[i])
+				}
+			}
+		})
+	}
+}
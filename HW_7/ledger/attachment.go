@@ -0,0 +1,353 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// attachmentFormField is the multipart field carrying the uploaded file.
+const attachmentFormField = "file"
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// SetBlobStore replaces where attachment contents are kept. Existing
+// attachments are not copied over.
+func (l *Ledger) SetBlobStore(store BlobStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.blobs = store
+}
+
+// AddAttachment stores the content of r and attaches it to the transaction.
+// The upload runs without holding the ledger lock; deleting orphaned blobs
+// waits for it. Transactions in closed or reconciled periods take no new
+// attachments.
+func (l *Ledger) AddAttachment(ctx context.Context, txID, filename string, r io.Reader) (*Attachment, error) {
+	l.mu.RLock()
+	err := ErrTransactionNotFound
+	if i := l.findTransaction(txID); i >= 0 {
+		err = l.checkEditable(l.Transactions[i])
+	}
+	blobs := l.blobs
+	l.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	l.uploads.RLock()
+	info, err := blobs.Put(ctx, r)
+	if err != nil {
+		l.uploads.RUnlock()
+		return nil, err
+	}
+
+	attachment := Attachment{
+		ID:          uuid.New().String(),
+		Filename:    filepath.Base(filename),
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		SHA256:      info.Key,
+		UploadedAt:  time.Now().UTC(),
+	}
+
+	// The period may have closed or the transaction gone during the upload.
+	l.mu.Lock()
+	i := l.findTransaction(txID)
+	err = ErrTransactionNotFound
+	if i >= 0 {
+		err = l.checkEditable(l.Transactions[i])
+	}
+	if err != nil {
+		l.mu.Unlock()
+		l.uploads.RUnlock()
+		l.deleteOrphanedBlobs(ctx, blobs, []Attachment{attachment})
+		return nil, err
+	}
+	updated := *l.Transactions[i]
+	updated.Attachments = append(slices.Clone(updated.Attachments), attachment)
+	l.replaceTransaction(i, &updated)
+	l.mu.Unlock()
+	l.uploads.RUnlock()
+
+	return &attachment, nil
+}
+
+func (l *Ledger) ListAttachments(txID string) ([]Attachment, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	i := l.findTransaction(txID)
+	if i < 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return slices.Clone(l.Transactions[i].Attachments), nil
+}
+
+// OpenAttachment returns an attachment with a reader over its content, which
+// the caller must close.
+func (l *Ledger) OpenAttachment(ctx context.Context, txID, attachmentID string) (*Attachment, io.ReadCloser, error) {
+	l.mu.RLock()
+	attachment, err := l.findAttachment(txID, attachmentID)
+	blobs := l.blobs
+	l.mu.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := blobs.Open(ctx, attachment.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (l *Ledger) DeleteAttachment(ctx context.Context, txID, attachmentID string) error {
+	l.mu.Lock()
+	attachment, err := l.findAttachment(txID, attachmentID)
+	if err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	i := l.findTransaction(txID)
+	if err := l.checkEditable(l.Transactions[i]); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	updated := *l.Transactions[i]
+	updated.Attachments = slices.DeleteFunc(slices.Clone(updated.Attachments), func(a Attachment) bool {
+		return a.ID == attachmentID
+	})
+	l.replaceTransaction(i, &updated)
+	blobs := l.blobs
+	l.mu.Unlock()
+
+	l.deleteOrphanedBlobs(ctx, blobs, []Attachment{*attachment})
+	return nil
+}
+
+func (l *Ledger) findAttachment(txID, attachmentID string) (*Attachment, error) {
+	i := l.findTransaction(txID)
+	if i < 0 {
+		return nil, ErrTransactionNotFound
+	}
+	for _, attachment := range l.Transactions[i].Attachments {
+		if attachment.ID == attachmentID {
+			return &attachment, nil
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// replaceTransaction swaps in an updated copy of the transaction at i.
+// Attachments change by copy so readers holding the old pointer are not
+// affected.
+func (l *Ledger) replaceTransaction(i int, tx *Transaction) {
+	old := l.Transactions[i]
+	l.Transactions[i] = tx
+	l.tags.replace(old, tx, l.Transactions)
+	l.transactionEvent(EventTransactionUpdated, tx)
+}
+
+// orphanedBlobs returns the blob keys of removed attachments that no remaining
+// transaction refers to. Identical uploads share a blob.
+func (l *Ledger) orphanedBlobs(removed []Attachment) []string {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	inUse := make(map[string]bool)
+	for _, tx := range l.Transactions {
+		for _, attachment := range tx.Attachments {
+			inUse[attachment.SHA256] = true
+		}
+	}
+
+	var orphans []string
+	for _, attachment := range removed {
+		if !inUse[attachment.SHA256] && !slices.Contains(orphans, attachment.SHA256) {
+			orphans = append(orphans, attachment.SHA256)
+		}
+	}
+	return orphans
+}
+
+// deleteOrphanedBlobs deletes the blobs of removed attachments once no
+// transaction refers to them. It waits for uploads in flight, which may have
+// stored the same content without referring to it yet, and looks for orphans
+// only after they finish. Failures only leak storage, so they are logged
+// rather than returned.
+func (l *Ledger) deleteOrphanedBlobs(ctx context.Context, blobs BlobStore, removed []Attachment) {
+	if len(removed) == 0 {
+		return
+	}
+
+	l.uploads.Lock()
+	defer l.uploads.Unlock()
+
+	l.mu.RLock()
+	orphans := l.orphanedBlobs(removed)
+	l.mu.RUnlock()
+
+	for _, key := range orphans {
+		if err := blobs.Delete(ctx, key); err != nil {
+			slog.WarnContext(ctx, "failed to delete attachment blob", "sha256", key, "error", err)
+		}
+	}
+}
+
+type AttachmentResponse struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	URL         string    `json:"url"`
+}
+
+func newAttachmentResponse(txID string, a Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          a.ID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		SHA256:      a.SHA256,
+		UploadedAt:  a.UploadedAt,
+		URL:         "/api/transactions/" + txID + "/attachments/" + a.ID,
+	}
+}
+
+func (h *Handler) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected multipart/form-data body")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "missing "+attachmentFormField+" field")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid multipart body")
+			return
+		}
+		if part.FormName() != attachmentFormField {
+			part.Close()
+			continue
+		}
+
+		txID := r.PathValue("id")
+		attachment, err := h.ledger.AddAttachment(r.Context(), txID, part.FileName(), part)
+		part.Close()
+		switch {
+		case errors.Is(err, ErrTransactionNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrPeriodClosed), errors.Is(err, ErrPeriodReconciled):
+			writeErrorCode(w, http.StatusConflict, ErrCodePeriodLocked, err.Error())
+		case errors.Is(err, ErrBlobTooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, ErrBlobTypeUnsupported):
+			writeError(w, http.StatusUnsupportedMediaType, err.Error())
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusCreated, newAttachmentResponse(txID, *attachment))
+		}
+		return
+	}
+}
+
+func (h *Handler) ListAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	txID := r.PathValue("id")
+	attachments, err := h.ledger.ListAttachments(txID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response := make([]AttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		response[i] = newAttachmentResponse(txID, attachment)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	attachment, content, err := h.ledger.OpenAttachment(r.Context(), r.PathValue("id"), r.PathValue("attachment"))
+	switch {
+	case errors.Is(err, ErrTransactionNotFound), errors.Is(err, ErrAttachmentNotFound), errors.Is(err, ErrBlobNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", attachment.UploadedAt, seeker)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+func (h *Handler) DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	err := h.ledger.DeleteAttachment(r.Context(), r.PathValue("id"), r.PathValue("attachment"))
+	switch {
+	case errors.Is(err, ErrPeriodClosed), errors.Is(err, ErrPeriodReconciled):
+		writeErrorCode(w, http.StatusConflict, ErrCodePeriodLocked, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,314 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	pngContent = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 600)...)
+	pdfContent = []byte("%PDF-1.4\n1 0 obj << >> endobj\n%%EOF\n")
+)
+
+func sha(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.MaxSize = 1024
+
+	info, err := store.Put(ctx, bytes.NewReader(pdfContent))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Key != sha(pdfContent) || info.ContentType != "application/pdf" || info.Size != int64(len(pdfContent)) {
+		t.Errorf("Unexpected blob info %+v", info)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, info.Key[:2], info.Key)); err != nil {
+		t.Errorf("Expected blob file, got %v", err)
+	}
+
+	t.Run("identical content is stored once", func(t *testing.T) {
+		again, err := store.Put(ctx, bytes.NewReader(pdfContent))
+		if err != nil || again.Key != info.Key {
+			t.Errorf("Expected same key, got %+v, %v", again, err)
+		}
+		entries, _ := os.ReadDir(store.Dir)
+		if len(entries) != 1 {
+			t.Errorf("Expected only the fan-out directory, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		content, err := store.Open(ctx, info.Key)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer content.Close()
+		if data, _ := io.ReadAll(content); !bytes.Equal(data, pdfContent) {
+			t.Error("Expected stored content back")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{"too large", append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte{' '}, 2048)...), ErrBlobTooLarge},
+		{"unsupported type", []byte("just some text"), ErrBlobTypeUnsupported},
+		{"empty", nil, ErrBlobTypeUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Put(ctx, bytes.NewReader(tt.content)); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("rejected uploads leave no files", func(t *testing.T) {
+		matches, _ := filepath.Glob(filepath.Join(store.Dir, ".upload-*"))
+		if len(matches) != 0 {
+			t.Errorf("Expected temporary files to be removed, got %v", matches)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		if _, err := store.Open(ctx, "../../etc/passwd"); err != ErrBlobNotFound {
+			t.Errorf("Expected %v, got %v", ErrBlobNotFound, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := store.Delete(ctx, info.Key); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := store.Open(ctx, info.Key); err != ErrBlobNotFound {
+			t.Errorf("Expected %v, got %v", ErrBlobNotFound, err)
+		}
+	})
+}
+
+func multipartBody(t *testing.T, field, filename string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("Failed to build multipart body: %v", err)
+	}
+	part.Write(content)
+	w.Close()
+	return &body, w.FormDataContentType()
+}
+
+func TestAttachmentHandlers(t *testing.T) {
+	ledger := NewLedger()
+	store, _ := NewLocalBlobStore(t.TempDir())
+	ledger.SetBlobStore(store)
+	for _, id := range []string{"hotel", "taxi"} {
+		ledger.AddTransaction(&Transaction{ID: id, Amount: 100, Category: "travel", Date: date("2024-07-01"), Type: "expense"})
+	}
+
+	handler := NewHandler(ledger)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions/{id}/attachments", handler.UploadAttachmentHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments", handler.ListAttachmentsHandler)
+	mux.HandleFunc("GET /api/transactions/{id}/attachments/{attachment}", handler.DownloadAttachmentHandler)
+	mux.HandleFunc("DELETE /api/transactions/{id}/attachments/{attachment}", handler.DeleteAttachmentHandler)
+
+	upload := func(txID, field string, content []byte) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(t, field, "receipt.png", content)
+		req := httptest.NewRequest("POST", "/api/transactions/"+txID+"/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+	blobExists := func(content []byte) bool {
+		_, err := os.Stat(store.path(sha(content)))
+		return err == nil
+	}
+
+	rr := upload("hotel", "file", pngContent)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var attachment AttachmentResponse
+	json.Unmarshal(rr.Body.Bytes(), &attachment)
+	if attachment.ContentType != "image/png" || attachment.SHA256 != sha(pngContent) || attachment.Filename != "receipt.png" {
+		t.Errorf("Unexpected attachment %+v", attachment)
+	}
+
+	t.Run("upload errors", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			rr     *httptest.ResponseRecorder
+			status int
+		}{
+			{"unknown transaction", upload("missing", "file", pngContent), http.StatusNotFound},
+			{"unsupported type", upload("hotel", "file", []byte("hello")), http.StatusUnsupportedMediaType},
+			{"missing field", upload("hotel", "document", pngContent), http.StatusBadRequest},
+			{"not multipart", do("POST", "/api/transactions/hotel/attachments"), http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			if tt.rr.Code != tt.status {
+				t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, tt.rr.Code)
+			}
+		}
+	})
+
+	t.Run("transaction response lists attachment", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ListTransactionsHandler(rr, httptest.NewRequest("GET", "/api/transactions", nil))
+		var transactions []TransactionResponse
+		json.Unmarshal(rr.Body.Bytes(), &transactions)
+		if len(transactions[0].Attachments) != 1 || transactions[0].Attachments[0].URL != attachment.URL {
+			t.Errorf("Expected attachment on hotel transaction, got %+v", transactions[0])
+		}
+	})
+
+	t.Run("download", func(t *testing.T) {
+		rr := do("GET", attachment.URL)
+		if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), pngContent) {
+			t.Fatalf("Expected attachment content, got status %d", rr.Code)
+		}
+		if rr.Header().Get("Content-Type") != "image/png" || !strings.Contains(rr.Header().Get("Content-Disposition"), "receipt.png") {
+			t.Errorf("Unexpected headers %v", rr.Header())
+		}
+		if rr := do("GET", "/api/transactions/hotel/attachments/missing"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("shared blob survives until last reference", func(t *testing.T) {
+		rr := upload("taxi", "file", pngContent)
+		var shared AttachmentResponse
+		json.Unmarshal(rr.Body.Bytes(), &shared)
+
+		if rr := do("DELETE", attachment.URL); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rr.Code)
+		}
+		if !blobExists(pngContent) {
+			t.Fatal("Expected blob shared with taxi to be kept")
+		}
+		if rr := do("GET", "/api/transactions/hotel/attachments"); rr.Body.String() != "[]\n" {
+			t.Errorf("Expected no hotel attachments, got %s", rr.Body.String())
+		}
+
+		if err := ledger.DeleteTransaction("taxi"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if blobExists(pngContent) {
+			t.Error("Expected blob to be removed with the last transaction using it")
+		}
+	})
+
+	t.Run("update keeps attachments", func(t *testing.T) {
+		upload("hotel", "file", pdfContent)
+		ledger.UpdateTransaction(&Transaction{ID: "hotel", Amount: 120, Category: "travel", Date: date("2024-07-01"), Type: "expense"})
+		if attachments, _ := ledger.ListAttachments("hotel"); len(attachments) != 1 {
+			t.Errorf("Expected attachment to survive update, got %v", attachments)
+		}
+	})
+
+	t.Run("closed period", func(t *testing.T) {
+		attachments, _ := ledger.ListAttachments("hotel")
+		if _, err := ledger.ClosePeriod("2024-07", "alice"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if rr := upload("hotel", "file", pngContent); rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), ErrCodePeriodLocked) {
+			t.Errorf("Expected upload to answer %d %s, got %d: %s", http.StatusConflict, ErrCodePeriodLocked, rr.Code, rr.Body.String())
+		}
+		if rr := do("DELETE", newAttachmentResponse("hotel", attachments[0]).URL); rr.Code != http.StatusConflict {
+			t.Errorf("Expected delete to answer %d, got %d", http.StatusConflict, rr.Code)
+		}
+		if after, _ := ledger.ListAttachments("hotel"); len(after) != len(attachments) {
+			t.Errorf("Expected attachments to stay as they were, got %v", after)
+		}
+		if !blobExists(pdfContent) {
+			t.Error("Expected the attached blob to be kept")
+		}
+	})
+}
+
+// pausedBlobStore holds each Put after storing the blob until released.
+type pausedBlobStore struct {
+	BlobStore
+	stored  chan struct{}
+	release chan struct{}
+}
+
+func (s *pausedBlobStore) Put(ctx context.Context, r io.Reader) (BlobInfo, error) {
+	info, err := s.BlobStore.Put(ctx, r)
+	s.stored <- struct{}{}
+	<-s.release
+	return info, err
+}
+
+func TestDeleteAttachment_UploadInFlight(t *testing.T) {
+	ledger := NewLedger()
+	for _, id := range []string{"hotel", "taxi"} {
+		ledger.AddTransaction(&Transaction{ID: id, Amount: 100, Category: "travel", Date: date("2024-07-01"), Type: "expense"})
+	}
+	ctx := context.Background()
+	old, err := ledger.AddAttachment(ctx, "hotel", "receipt.png", bytes.NewReader(pngContent))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The same receipt is uploaded to taxi while hotel's copy is deleted.
+	store := &pausedBlobStore{BlobStore: ledger.blobs, stored: make(chan struct{}), release: make(chan struct{})}
+	ledger.SetBlobStore(store)
+	uploaded := make(chan *Attachment)
+	go func() {
+		attachment, err := ledger.AddAttachment(ctx, "taxi", "receipt.png", bytes.NewReader(pngContent))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		uploaded <- attachment
+	}()
+	<-store.stored
+
+	deleted := make(chan error)
+	go func() { deleted <- ledger.DeleteAttachment(ctx, "hotel", old.ID) }()
+	time.Sleep(10 * time.Millisecond)
+	close(store.release)
+	attachment := <-uploaded
+	if err := <-deleted; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, content, err := ledger.OpenAttachment(ctx, "taxi", attachment.ID)
+	if err != nil {
+		t.Fatalf("Expected the uploaded blob to be kept, got %v", err)
+	}
+	content.Close()
+}
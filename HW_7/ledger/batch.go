@@ -0,0 +1,196 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrBatchRejected is reported for the transactions of an atomic batch that
+// passed their checks but were not added because others failed.
+var ErrBatchRejected = errors.New("batch rejected")
+
+// MaxBatchSize caps the transactions accepted by one batch request.
+const MaxBatchSize = 50000
+
+// maxBatchBody caps the size of a batch request, ample for MaxBatchSize
+// transactions.
+const maxBatchBody = 64 << 20
+
+// BatchMode decides what AddTransactions does when some transactions fail.
+type BatchMode string
+
+const (
+	// BatchAtomic adds every transaction or none.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort adds every transaction that passes its checks.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+func ParseBatchMode(s string) (BatchMode, error) {
+	switch mode := BatchMode(s); mode {
+	case BatchAtomic, BatchBestEffort:
+		return mode, nil
+	default:
+		return "", errors.New("mode must be 'atomic' or 'best_effort'")
+	}
+}
+
+// AddTransactions adds txs in order under a single lock and returns the error
+// of each, nil for those added. Budgets are checked cumulatively: every
+// transaction counts the spending of those admitted before it in the batch.
+// In atomic mode one failure leaves the ledger unchanged, and the
+// transactions that passed report ErrBatchRejected. Budgets are notified once
+// per category the batch touched.
+func (l *Ledger) AddTransactions(ctx context.Context, txs []*Transaction, mode BatchMode) []error {
+	_, span := startSpan(ctx, "Ledger.AddTransactions",
+		attribute.Int("ledger.batch_size", len(txs)),
+		attribute.String("ledger.batch_mode", string(mode)),
+	)
+	defer span.End()
+
+	for _, tx := range txs {
+		tx.fileSplit()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	errs := make([]error, len(txs))
+	batch := newSpendingIndex()
+	failed := 0
+	for i, tx := range txs {
+		if err := l.admit(tx); err != nil {
+			errs[i] = err
+			failed++
+			continue
+		}
+		if err := l.overBudget(tx, nil, batch); err != nil {
+			errs[i] = err
+			failed++
+			continue
+		}
+		batch.add(tx)
+	}
+	span.SetAttributes(attribute.Int("ledger.batch_failed", failed))
+
+	if failed > 0 && mode == BatchAtomic {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = ErrBatchRejected
+			}
+		}
+		return errs
+	}
+
+	l.Transactions = slices.Grow(l.Transactions, len(txs)-failed)
+	added := make([]*Transaction, 0, len(txs)-failed)
+	for i, tx := range txs {
+		if errs[i] == nil {
+			l.store(tx)
+			added = append(added, tx)
+		}
+	}
+	l.notifyBudgets(added...)
+	return errs
+}
+
+type BatchTransactionsRequest struct {
+	Mode         string                     `json:"mode" enum:"atomic,best_effort"`
+	Transactions []CreateTransactionRequest `json:"transactions"`
+}
+
+// BatchResultResponse is the outcome of one transaction of a batch, in
+// request order: the ID it was added under, or why it was not.
+type BatchResultResponse struct {
+	ID    string         `json:"id,omitempty"`
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type BatchTransactionsResponse struct {
+	Mode    string                `json:"mode" enum:"atomic,best_effort"`
+	Created int                   `json:"created"`
+	Failed  int                   `json:"failed"`
+	Results []BatchResultResponse `json:"results"`
+}
+
+// CreateTransactionsBatchHandler answers 201 when an atomic batch is added,
+// 422 when it is rejected and 200 for best-effort batches, each with a result
+// per transaction.
+func (h *Handler) CreateTransactionsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req BatchTransactionsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "batch request too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+	mode, err := ParseBatchMode(req.Mode)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Transactions) == 0 || len(req.Transactions) > MaxBatchSize {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("batch must hold 1 to %d transactions", MaxBatchSize))
+		return
+	}
+
+	response := BatchTransactionsResponse{Mode: string(mode), Results: make([]BatchResultResponse, len(req.Transactions))}
+	txs := make([]*Transaction, 0, len(req.Transactions))
+	positions := make([]int, 0, len(req.Transactions))
+	for i, item := range req.Transactions {
+		tx, err := item.transaction()
+		if err != nil {
+			response.Results[i].Error = &ErrorResponse{Error: err.Error(), Code: ErrCodeValidation}
+			continue
+		}
+		txs = append(txs, tx)
+		positions = append(positions, i)
+	}
+
+	// An atomic batch with malformed items fails before reaching the ledger.
+	var errs []error
+	if mode == BatchAtomic && len(txs) < len(req.Transactions) {
+		errs = make([]error, len(txs))
+		for i := range errs {
+			errs[i] = ErrBatchRejected
+		}
+	} else {
+		errs = h.ledger.AddTransactions(r.Context(), txs, mode)
+	}
+
+	for i, err := range errs {
+		result := &response.Results[positions[i]]
+		if err != nil {
+			_, code := transactionErrorStatus(err)
+			result.Error = &ErrorResponse{Error: err.Error(), Code: code}
+			continue
+		}
+		result.ID = txs[i].ID
+		response.Created++
+	}
+	response.Failed = len(req.Transactions) - response.Created
+	Logger(r.Context()).Info("transaction batch processed", "mode", mode, "created", response.Created, "failed", response.Failed)
+
+	status := http.StatusOK
+	if mode == BatchAtomic {
+		status = http.StatusCreated
+		if response.Failed > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	writeJSON(w, status, response)
+}
@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func batch() []*Transaction {
+	return []*Transaction{
+		{ID: "a", Amount: 60, Category: "food", Date: date("2024-03-01"), Type: "expense"},
+		{ID: "b", Amount: 30, Category: "food", Date: date("2024-03-02"), Type: "expense"},
+		{ID: "c", Amount: 20, Category: "food", Date: date("2024-03-03"), Type: "expense"},
+		{ID: "d", Amount: 0, Category: "food", Date: date("2024-03-04"), Type: "expense"},
+		{ID: "e", Amount: 1000, Category: "salary", Date: date("2024-03-05"), Type: "income"},
+	}
+}
+
+func TestLedger_AddTransactions(t *testing.T) {
+	// Each transaction is expected to be added (""), rejected for the batch,
+	// over budget or invalid.
+	tests := []struct {
+		name   string
+		mode   BatchMode
+		added  []string
+		status []string
+	}{
+		{"atomic", BatchAtomic, nil, []string{"rejected", "rejected", "budget", "invalid", "rejected"}},
+		{"best effort", BatchBestEffort, []string{"a", "b", "e"}, []string{"", "", "budget", "invalid", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := NewLedger()
+			ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+
+			errs := ledger.AddTransactions(context.Background(), batch(), tt.mode)
+			if len(errs) != len(tt.status) {
+				t.Fatalf("Expected %d results, got %d", len(tt.status), len(errs))
+			}
+			for i, err := range errs {
+				var got string
+				switch {
+				case err == nil:
+				case errors.Is(err, ErrBatchRejected):
+					got = "rejected"
+				case errors.Is(err, ErrBudgetExceeded):
+					got = "budget"
+				default:
+					got = "invalid"
+				}
+				if got != tt.status[i] {
+					t.Errorf("%d: expected %q, got %q (%v)", i, tt.status[i], got, err)
+				}
+			}
+
+			if got := ids(ledger.ListTransactions()); got != strings.Join(tt.added, ",") {
+				t.Errorf("Expected %v added, got %v", tt.added, got)
+			}
+			if err := ledger.CheckConsistency(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLedger_AddTransactions_Large(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100000})
+	txs := make([]*Transaction, 20000)
+	for i := range txs {
+		txs[i] = &Transaction{ID: fmt.Sprint(i), Amount: 1, Category: "food", Date: date("2024-03-01"), Type: "expense"}
+	}
+
+	for _, err := range ledger.AddTransactions(context.Background(), txs, BatchAtomic) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if spent := ledger.GetCategorySpending("food"); spent != 20000 {
+		t.Errorf("Expected 20000 spent, got %.2f", spent)
+	}
+	changed := 0
+	for _, e := range ledger.events.buffer {
+		if e.Type == EventBudgetChanged {
+			changed++
+		}
+	}
+	if changed != 1 {
+		t.Errorf("Expected one budget change for the batch, got %d", changed)
+	}
+}
+
+func TestCreateTransactionsBatchHandler(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 50})
+	handler := NewHandler(ledger)
+
+	body := `{"mode":"best_effort","transactions":[
+		{"amount":30,"category":"food","date":"2024-03-01","type":"expense"},
+		{"amount":30,"category":"food","date":"2024-03-02","type":"expense"},
+		{"amount":10,"category":"food","date":"March 3","type":"expense"},
+		{"amount":10,"category":"food","date":"2024-03-04","type":"expense"}]}`
+	rr := httptest.NewRecorder()
+	handler.CreateTransactionsBatchHandler(rr, httptest.NewRequest("POST", "/api/transactions:batch", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response BatchTransactionsResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Created != 2 || response.Failed != 2 || len(response.Results) != 4 {
+		t.Fatalf("Unexpected response %+v", response)
+	}
+	codes := []string{"", ErrCodeBudgetExceeded, ErrCodeValidation, ""}
+	for i, result := range response.Results {
+		if codes[i] == "" {
+			if result.Error != nil || result.ID == "" {
+				t.Errorf("%d: expected an ID, got %+v", i, result)
+			}
+			continue
+		}
+		if result.Error == nil || result.Error.Code != codes[i] {
+			t.Errorf("%d: expected %s, got %+v", i, codes[i], result.Error)
+		}
+	}
+
+	body = `{"mode":"atomic","transactions":[
+		{"amount":5,"category":"food","date":"2024-03-05","type":"expense"},
+		{"amount":5,"category":"food","date":"soon","type":"expense"}]}`
+	rr = httptest.NewRecorder()
+	handler.CreateTransactionsBatchHandler(rr, httptest.NewRequest("POST", "/api/transactions:batch", strings.NewReader(body)))
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if rr.Code != http.StatusUnprocessableEntity || response.Results[0].Error.Code != ErrCodeBatchRejected {
+		t.Errorf("Expected status %d with the valid item rejected, got %d: %s", http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	}
+	if n := len(ledger.ListTransactions()); n != 2 {
+		t.Errorf("Expected 2 transactions, got %d", n)
+	}
+
+	body = `{"mode":"atomic","transactions":[` + strings.Repeat(" ", maxBatchBody)
+	rr = httptest.NewRecorder()
+	handler.CreateTransactionsBatchHandler(rr, httptest.NewRequest("POST", "/api/transactions:batch", strings.NewReader(body)))
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+	}
+}
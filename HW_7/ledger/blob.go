@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrBlobNotFound        = errors.New("blob not found")
+	ErrBlobTooLarge        = errors.New("attachment too large")
+	ErrBlobTypeUnsupported = errors.New("unsupported attachment type")
+)
+
+const DefaultMaxBlobSize = 10 << 20
+
+// DefaultBlobTypes are the receipt formats accepted by default. Types are
+// sniffed from the content, not taken from the client.
+var DefaultBlobTypes = []string{"application/pdf", "image/jpeg", "image/png", "image/gif", "image/webp"}
+
+type BlobInfo struct {
+	// Key is the hex SHA-256 of the content.
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// BlobStore keeps attachment contents addressed by their SHA-256, so the same
+// receipt uploaded twice is stored once. Put enforces the store's size and
+// type limits.
+type BlobStore interface {
+	Put(ctx context.Context, r io.Reader) (BlobInfo, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type blobLimits struct {
+	maxSize      int64
+	allowedTypes []string
+}
+
+// copyBlob copies r to dst while hashing it, rejecting content over maxSize
+// or of a type not in allowedTypes.
+func (lim blobLimits) copyBlob(dst io.Writer, r io.Reader) (BlobInfo, error) {
+	limited := io.LimitReader(r, lim.maxSize+1)
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(limited, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return BlobInfo{}, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !slices.Contains(lim.allowedTypes, contentType) {
+		return BlobInfo{}, fmt.Errorf("%w: %s", ErrBlobTypeUnsupported, contentType)
+	}
+
+	sum := sha256.New()
+	w := io.MultiWriter(dst, sum)
+	if _, err := w.Write(head); err != nil {
+		return BlobInfo{}, err
+	}
+	rest, err := io.Copy(w, limited)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	size := int64(n) + rest
+	if size > lim.maxSize {
+		return BlobInfo{}, fmt.Errorf("%w: limit is %d bytes", ErrBlobTooLarge, lim.maxSize)
+	}
+	return BlobInfo{Key: hexSum(sum), Size: size, ContentType: contentType}, nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validBlobKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+type memoryBlobStore struct {
+	blobLimits
+
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func newMemoryBlobStore() *memoryBlobStore {
+	return &memoryBlobStore{
+		blobLimits: blobLimits{maxSize: DefaultMaxBlobSize, allowedTypes: DefaultBlobTypes},
+		blobs:      make(map[string][]byte),
+	}
+}
+
+func (s *memoryBlobStore) Put(ctx context.Context, r io.Reader) (BlobInfo, error) {
+	var buf bytes.Buffer
+	info, err := s.copyBlob(&buf, r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blobs[info.Key] = buf.Bytes()
+	return info, nil
+}
+
+func (s *memoryBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, exists := s.blobs[key]
+	if !exists {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.blobs, key)
+	return nil
+}
+
+// LocalBlobStore keeps blobs as files under Dir, fanned out by the first two
+// hex digits of their hash.
+type LocalBlobStore struct {
+	Dir          string
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{
+		Dir:          dir,
+		MaxSize:      DefaultMaxBlobSize,
+		AllowedTypes: DefaultBlobTypes,
+	}, nil
+}
+
+func (s *LocalBlobStore) path(key string) string {
+	return filepath.Join(s.Dir, key[:2], key)
+}
+
+// Put streams r to a temporary file and renames it into place once its hash
+// is known, so a partial upload never becomes visible.
+func (s *LocalBlobStore) Put(ctx context.Context, r io.Reader) (BlobInfo, error) {
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	limits := blobLimits{maxSize: s.MaxSize, allowedTypes: s.AllowedTypes}
+	info, err := limits.copyBlob(tmp, r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	path := s.path(info.Key)
+	if _, err := os.Stat(path); err == nil {
+		return info, nil
+	}
+	if err := tmp.Sync(); err != nil {
+		return BlobInfo{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return BlobInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return BlobInfo{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return BlobInfo{}, err
+	}
+	return info, nil
+}
+
+func (s *LocalBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validBlobKey(key) {
+		return nil, ErrBlobNotFound
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	if !validBlobKey(key) {
+		return ErrBlobNotFound
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dashboardPageSize is how many transactions the dashboard lists per page.
+const dashboardPageSize = 50
+
+// dashboardMonths is how many months the dashboard charts, ending with the
+// current one.
+const dashboardMonths = 12
+
+// maxDashboardForm caps the size of an add-transaction form.
+const maxDashboardForm = 64 << 10
+
+// dashboardCSRFCookie holds the token the add-transaction form must echo, so
+// other sites cannot post it on the user's behalf.
+const dashboardCSRFCookie = "ledger_csrf"
+
+//go:embed dashboard
+var dashboardFS embed.FS
+
+var dashboardTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(dateLayout)
+	},
+}).ParseFS(dashboardFS, "dashboard/*.html"))
+
+type dashboardPage struct {
+	Error        string
+	Filter       TransactionFilter
+	Transactions []TransactionResponse
+	// PrevPage and NextPage are the queries of the neighbouring pages, empty
+	// when there is none.
+	PrevPage string
+	NextPage string
+	// Form holds the values of a rejected add-transaction form.
+	Form      url.Values
+	CSRFToken string
+	Today     string
+	Budgets   []dashboardBudget
+	Months    []dashboardMonth
+}
+
+type dashboardBudget struct {
+	BudgetResponse
+	// Percent is Spent as a share of Limit, capped at 100 for the bar.
+	Percent float64
+	Over    bool
+}
+
+type dashboardMonth struct {
+	Period   string
+	Income   float64
+	Expenses float64
+	// IncomeHeight and ExpensesHeight scale the bars to the busiest month.
+	IncomeHeight   float64
+	ExpensesHeight float64
+}
+
+// DashboardHandler serves the web dashboard: transactions matching the
+// query's filters, budget progress, monthly totals and a form adding a
+// transaction. The form posts back here and is redirected to the dashboard,
+// or shown again with the error. Posts must come from the dashboard itself:
+// same-origin and carrying the token of its cookie.
+func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		filter, err := ParseTransactionFilter(r.URL.Query())
+		if err != nil {
+			h.renderDashboard(w, r, http.StatusBadRequest, TransactionFilter{}, nil, err.Error())
+			return
+		}
+		h.renderDashboard(w, r, http.StatusOK, filter, nil, "")
+	case http.MethodPost:
+		h.addDashboardTransaction(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) addDashboardTransaction(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, "cross-origin form post")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxDashboardForm)
+	if err := r.ParseForm(); err != nil {
+		h.renderDashboard(w, r, http.StatusBadRequest, TransactionFilter{}, nil, "invalid form")
+		return
+	}
+	cookie, err := r.Cookie(dashboardCSRFCookie)
+	if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get("csrf_token"))) != 1 {
+		writeError(w, http.StatusForbidden, "invalid form token")
+		return
+	}
+
+	req := CreateTransactionRequest{
+		Category:    r.PostForm.Get("category"),
+		Description: r.PostForm.Get("description"),
+		Date:        r.PostForm.Get("date"),
+		Type:        r.PostForm.Get("type"),
+	}
+	for _, tag := range strings.Split(r.PostForm.Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			req.Tags = append(req.Tags, tag)
+		}
+	}
+	amount, err := strconv.ParseFloat(r.PostForm.Get("amount"), 64)
+	if err != nil {
+		h.renderDashboard(w, r, http.StatusBadRequest, TransactionFilter{}, r.PostForm, "amount must be a number")
+		return
+	}
+	req.Amount = amount
+
+	tx, err := req.transaction()
+	if err != nil {
+		h.renderDashboard(w, r, http.StatusBadRequest, TransactionFilter{}, r.PostForm, err.Error())
+		return
+	}
+	if err := h.ledger.AddTransactionContext(r.Context(), tx); err != nil {
+		status, _ := transactionErrorStatus(err)
+		h.renderDashboard(w, r, status, TransactionFilter{}, r.PostForm, err.Error())
+		return
+	}
+
+	Logger(r.Context()).Info("transaction created from dashboard", "id", tx.ID)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// sameOrigin reports whether r was sent from a page of this server, judged by
+// its Origin header or, without one, its Referer.
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Referer()
+	}
+	if source == "" {
+		return true
+	}
+	u, err := url.Parse(source)
+	return err == nil && u.Host == r.Host
+}
+
+// csrfToken returns the token of the dashboard cookie, setting a new one when
+// the request has none.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(dashboardCSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	token := make([]byte, 16)
+	rand.Read(token)
+	value := hex.EncodeToString(token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCSRFCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return value
+}
+
+func (h *Handler) renderDashboard(w http.ResponseWriter, r *http.Request, status int, filter TransactionFilter, form url.Values, message string) {
+	asOf := today()
+	page := dashboardPage{
+		Error:     message,
+		Filter:    filter,
+		Form:      form,
+		CSRFToken: csrfToken(w, r),
+		Today:     asOf.Format(dateLayout),
+		Months:    h.ledger.monthlyTotals(asOf, dashboardMonths),
+	}
+
+	// Fetch one extra transaction to learn whether there is a next page.
+	pageSize := filter.Limit
+	if pageSize == 0 {
+		pageSize = dashboardPageSize
+	}
+	filter.Limit = pageSize + 1
+	transactions := h.ledger.FilterTransactions(filter)
+	if len(transactions) > pageSize {
+		transactions = transactions[:pageSize]
+		next := page.Filter
+		next.Offset += pageSize
+		page.NextPage = "?" + next.Query().Encode()
+	}
+	if filter.Offset > 0 {
+		prev := page.Filter
+		prev.Offset = max(0, prev.Offset-pageSize)
+		page.PrevPage = "?" + prev.Query().Encode()
+	}
+	for _, tx := range transactions {
+		page.Transactions = append(page.Transactions, newTransactionResponse(tx))
+	}
+
+	for _, budget := range h.ledger.Summary(asOf).Budgets {
+		b := dashboardBudget{BudgetResponse: newBudgetResponse(budget), Over: budget.Spent > budget.Limit}
+		if budget.Limit > 0 {
+			b.Percent = min(100, budget.Spent/budget.Limit*100)
+		}
+		page.Budgets = append(page.Budgets, b)
+	}
+
+	var body bytes.Buffer
+	if err := dashboardTemplate.Execute(&body, page); err != nil {
+		Logger(r.Context()).Error("failed to render dashboard", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to render dashboard")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
+
+// monthlyTotals returns the posted income and expenses of the given number of
+// months, oldest first, ending with the month containing asOf.
+func (l *Ledger) monthlyTotals(asOf time.Time, months int) []dashboardMonth {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	start := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, asOf.Location())
+	totals := make([]dashboardMonth, months)
+	for i := range totals {
+		totals[i].Period = periodKey(start.AddDate(0, i-months+1, 0))
+	}
+	for _, tx := range l.Transactions {
+		back := periodsBefore(start, tx.Date)
+		if back < 0 || back >= months || tx.Status == StatusPending {
+			continue
+		}
+		switch tx.Type {
+		case "income":
+			totals[months-1-back].Income += tx.Amount
+		case "expense":
+			totals[months-1-back].Expenses += tx.Amount
+		}
+	}
+
+	var peak float64
+	for _, m := range totals {
+		peak = max(peak, m.Income, m.Expenses)
+	}
+	if peak > 0 {
+		for i := range totals {
+			totals[i].IncomeHeight = totals[i].Income / peak * 100
+			totals[i].ExpensesHeight = totals[i].Expenses / peak * 100
+		}
+	}
+	return totals
+}
+
+// DashboardAssetsHandler serves the dashboard's stylesheet and script under
+// /static/.
+func DashboardAssetsHandler() http.Handler {
+	static, err := fs.Sub(dashboardFS, "dashboard/static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/static/", http.FileServerFS(static))
+}
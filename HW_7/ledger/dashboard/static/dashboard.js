@@ -0,0 +1,13 @@
+// Offer a reload when the ledger changes while the dashboard is open.
+(function () {
+  if (!window.EventSource) {
+    return;
+  }
+  var banner = document.getElementById("changes");
+  var events = new EventSource("/api/events");
+  ["transaction.created", "transaction.updated", "transaction.deleted", "budget.changed", "resync"].forEach(function (type) {
+    events.addEventListener(type, function () {
+      banner.hidden = false;
+    });
+  });
+})();
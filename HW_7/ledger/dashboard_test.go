@@ -0,0 +1,129 @@
+package ledger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dashboardLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 200})
+	now := time.Now()
+	mustAdd(t, ledger,
+		&Transaction{ID: "salary", Amount: 3000, Category: "salary", Description: "Paycheck", Date: now.AddDate(0, -1, 0), Type: "income"},
+		&Transaction{ID: "groceries", Amount: 150, Category: "food", Description: "Groceries", Date: now, Type: "expense", Tags: []string{"weekly"}},
+		&Transaction{ID: "cinema", Amount: 30, Category: "fun", Description: "Cinema <3", Date: now, Type: "expense"},
+	)
+	return ledger
+}
+
+func TestDashboardHandler(t *testing.T) {
+	handler := NewHandler(dashboardLedger(t))
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		want    []string
+		notWant []string
+	}{
+		{"all", "", http.StatusOK, []string{"Paycheck", "Groceries", "Cinema &lt;3", `style="width: 75.0%"`, "150.00 / 200.00", `class="tag">weekly`, `style="height: 100.0%"`}, nil},
+		{"filtered", "?type=expense&tag=weekly", http.StatusOK, []string{"Groceries", `<option value="expense" selected>`, `name="tag" value="weekly"`}, []string{"Paycheck", "Cinema"}},
+		{"paged", "?limit=1&offset=1", http.StatusOK, []string{"Groceries", `href="?limit=1"`, `href="?limit=1&amp;offset=2"`}, []string{"Paycheck"}},
+		{"invalid filter", "?from=yesterday", http.StatusBadRequest, []string{"invalid from date", "Paycheck"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.DashboardHandler(rr, httptest.NewRequest("GET", "/"+tt.query, nil))
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			body := rr.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected page to contain %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("Expected page not to contain %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestDashboardHandler_AddTransaction(t *testing.T) {
+	ledger := dashboardLedger(t)
+	handler := NewHandler(ledger)
+	today := time.Now().Format(dateLayout)
+
+	// The form echoes the token of the cookie set when it was rendered.
+	rr := httptest.NewRecorder()
+	handler.DashboardHandler(rr, httptest.NewRequest("GET", "/", nil))
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || !strings.Contains(rr.Body.String(), `name="csrf_token" value="`+cookies[0].Value+`"`) {
+		t.Fatalf("Expected a form token matching the cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		origin string
+		status int
+		want   string
+	}{
+		{"added", url.Values{"amount": {"20"}, "type": {"expense"}, "category": {"food"}, "date": {today}, "tags": {"lunch, work"}, "csrf_token": {token}}, "", http.StatusSeeOther, ""},
+		{"over budget", url.Values{"amount": {"99"}, "type": {"expense"}, "category": {"food"}, "date": {today}, "description": {"Feast"}, "csrf_token": {token}}, "http://example.com", http.StatusConflict, `value="Feast"`},
+		{"bad amount", url.Values{"amount": {"lots"}, "type": {"expense"}, "category": {"food"}, "date": {today}, "csrf_token": {token}}, "", http.StatusBadRequest, "amount must be a number"},
+		{"bad date", url.Values{"amount": {"5"}, "type": {"income"}, "category": {"gift"}, "date": {"soon"}, "csrf_token": {token}}, "", http.StatusBadRequest, `<option value="income" selected>`},
+		{"missing token", url.Values{"amount": {"5"}, "type": {"expense"}, "category": {"fun"}, "date": {today}}, "", http.StatusForbidden, "invalid form token"},
+		{"cross origin", url.Values{"amount": {"5"}, "type": {"expense"}, "category": {"fun"}, "date": {today}, "csrf_token": {token}}, "http://evil.test", http.StatusForbidden, "cross-origin"},
+		{"too large", url.Values{"amount": {"5"}, "description": {strings.Repeat("x", maxDashboardForm)}, "csrf_token": {token}}, "", http.StatusBadRequest, "invalid form"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(&http.Cookie{Name: dashboardCSRFCookie, Value: token})
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			handler.DashboardHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("Expected page to contain %q", tt.want)
+			}
+		})
+	}
+
+	txs := ledger.FilterTransactions(TransactionFilter{Tag: "work"})
+	if len(txs) != 1 || txs[0].Amount != 20 || strings.Join(txs[0].Tags, ",") != "lunch,work" {
+		t.Errorf("Expected the form's transaction to be added, got %+v", txs)
+	}
+}
+
+func TestDashboardAssetsHandler(t *testing.T) {
+	for _, path := range []string{"/static/dashboard.css", "/static/dashboard.js"} {
+		rr := httptest.NewRecorder()
+		DashboardAssetsHandler().ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusOK || rr.Body.Len() == 0 {
+			t.Errorf("%s: expected the embedded asset, got status %d", path, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	EventTransactionCreated = "transaction.created"
+	EventTransactionUpdated = "transaction.updated"
+	EventTransactionDeleted = "transaction.deleted"
+	EventBudgetChanged      = "budget.changed"
+	// EventResync tells a resuming subscriber that events were missed and
+	// it should reload its state. Its ID is that of the latest event.
+	EventResync = "resync"
+)
+
+const (
+	// eventBufferSize is how many recent events a ledger keeps for
+	// subscribers resuming after a disconnect.
+	eventBufferSize = 1024
+	// eventSubscriberBuffer is how far a subscriber may fall behind before
+	// it is dropped.
+	eventSubscriberBuffer = 256
+	// eventHeartbeat keeps idle streams open through proxies.
+	eventHeartbeat = 15 * time.Second
+)
+
+// Event is a change to a ledger. IDs increase by one per event of the ledger
+// and restart with the process.
+type Event struct {
+	ID          uint64
+	Type        string
+	At          time.Time
+	Transaction *Transaction
+	Budget      *Budget
+}
+
+// eventBus keeps the latest events and fans new ones out to subscribers.
+// Publishing never blocks: a subscriber that falls behind has its channel
+// closed and can resume from the buffer.
+type eventBus struct {
+	mu     sync.Mutex
+	last   uint64
+	buffer []Event
+	next   int
+	subs   map[int]chan Event
+	closed bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[int]chan Event)}
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last++
+	e.ID = b.last
+	e.At = time.Now().UTC()
+	if len(b.buffer) == eventBufferSize {
+		b.buffer = b.buffer[1:]
+	}
+	b.buffer = append(b.buffer, e)
+
+	for id, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, id)
+			close(ch)
+		}
+	}
+}
+
+// close ends every subscription, and those made later, so streams return and
+// the server can drain on shutdown.
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for id, ch := range b.subs {
+		delete(b.subs, id)
+		close(ch)
+	}
+}
+
+// EventSubscription delivers a ledger's events from the point it was
+// created.
+type EventSubscription struct {
+	// Backlog holds the buffered events after the ID the subscriber resumed
+	// from.
+	Backlog []Event
+	// Events receives later events. It is closed by Close, when the
+	// subscriber falls behind or when the ledger's events are closed.
+	Events <-chan Event
+
+	close func()
+}
+
+func (s *EventSubscription) Close() {
+	s.close()
+}
+
+// SubscribeEvents subscribes to the ledger's events, first replaying the
+// buffered events after lastID. IDs start at 1, so a lastID of 0 replays
+// nothing. When some of those events have left the buffer, or lastID predates
+// a restart, the backlog is a single resync event instead.
+func (l *Ledger) SubscribeEvents(lastID uint64) *EventSubscription {
+	b := l.events
+	ch := make(chan Event, eventSubscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &EventSubscription{Events: ch}
+	switch {
+	case lastID == 0:
+	case lastID > b.last || (len(b.buffer) > 0 && lastID < b.buffer[0].ID-1):
+		sub.Backlog = []Event{{ID: b.last, Type: EventResync, At: time.Now().UTC()}}
+	default:
+		for _, e := range b.buffer {
+			if e.ID > lastID {
+				sub.Backlog = append(sub.Backlog, e)
+			}
+		}
+	}
+
+	if b.closed {
+		close(ch)
+		sub.close = func() {}
+		return sub
+	}
+
+	id := b.next
+	b.next++
+	b.subs[id] = ch
+
+	var once sync.Once
+	sub.close = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, exists := b.subs[id]; exists {
+				delete(b.subs, id)
+				close(ch)
+			}
+		})
+	}
+	return sub
+}
+
+// CloseEvents ends every event subscription, current and future. Servers
+// call it on shutdown so open event streams do not hold it up.
+func (l *Ledger) CloseEvents() {
+	l.events.close()
+}
+
+func (l *Ledger) transactionEvent(eventType string, tx *Transaction) {
+	l.events.publish(Event{Type: eventType, Transaction: tx})
+}
+
+// budgetEvent publishes the budget with its current spending.
+func (l *Ledger) budgetEvent(budget *Budget) {
+	l.events.publish(Event{Type: EventBudgetChanged, Budget: &Budget{
+		Category:  budget.Category,
+		Limit:     budget.Limit,
+		Spent:     l.spending.total(budget.Category),
+		Committed: l.spending.committed(budget.Category),
+	}})
+}
+
+// EventResponse is the data of a server-sent event; the event's type and ID
+// are sent in its event and id fields.
+type EventResponse struct {
+	Type        string               `json:"type" enum:"transaction.created,transaction.updated,transaction.deleted,budget.changed,resync"`
+	At          time.Time            `json:"at"`
+	Transaction *TransactionResponse `json:"transaction,omitempty"`
+	Budget      *BudgetResponse      `json:"budget,omitempty"`
+}
+
+func newEventResponse(e Event) EventResponse {
+	response := EventResponse{Type: e.Type, At: e.At}
+	if e.Transaction != nil {
+		tx := newTransactionResponse(e.Transaction)
+		response.Transaction = &tx
+	}
+	if e.Budget != nil {
+		budget := newBudgetResponse(*e.Budget)
+		response.Budget = &budget
+	}
+	return response
+}
+
+type EventsQuery struct {
+	// LastEventID resumes like the Last-Event-ID header, for clients that
+	// cannot set headers.
+	LastEventID uint64 `query:"last_event_id"`
+}
+
+// EventsHandler streams the ledger's events as server-sent events. A client
+// reconnecting with Last-Event-ID first receives the events it missed, or a
+// resync event if they are no longer buffered. Each ledger has its own
+// stream, so callers only see changes to the ledger they talk to.
+func (h *Handler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid last event ID")
+			return
+		}
+		lastID = id
+	}
+
+	// Streams outlive the server's write timeout.
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	sub := h.ledger.SubscribeEvents(lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range sub.Backlog {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes e in event stream format. Only a resync before any event
+// lacks an ID.
+func writeEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(newEventResponse(e))
+	if err != nil {
+		return err
+	}
+	if e.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", e.ID)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
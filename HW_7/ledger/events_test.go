@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func eventTypes(events []Event) string {
+	types := make([]string, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return strings.Join(types, ",")
+}
+
+func TestLedger_Events(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+	ledger.AddTransaction(&Transaction{ID: "a", Amount: 10, Category: "food", Date: date("2024-03-01"), Type: "expense"})
+	ledger.UpdateTransaction(&Transaction{ID: "a", Amount: 20, Category: "food", Date: date("2024-03-01"), Type: "expense"})
+	ledger.DeleteTransaction("a")
+
+	want := "budget.changed,transaction.created,budget.changed,transaction.updated,budget.changed,transaction.deleted,budget.changed"
+	sub := ledger.SubscribeEvents(0)
+	defer sub.Close()
+	if len(sub.Backlog) != 0 {
+		t.Errorf("Expected no backlog, got %v", eventTypes(sub.Backlog))
+	}
+
+	tests := []struct {
+		name   string
+		lastID uint64
+		want   string
+	}{
+		{"resume", 3, strings.Join(strings.Split(want, ",")[3:], ",")},
+		{"up to date", 7, ""},
+		{"after restart", 100, EventResync},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := ledger.SubscribeEvents(tt.lastID)
+			defer sub.Close()
+			if got := eventTypes(sub.Backlog); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	ledger.SetBudget(&Budget{Category: "food", Limit: 50})
+	e := <-sub.Events
+	if e.ID != 8 || e.Type != EventBudgetChanged || e.Budget.Limit != 50 {
+		t.Errorf("Expected the budget change, got %+v", e)
+	}
+}
+
+func TestLedger_EventsOverflow(t *testing.T) {
+	ledger := NewLedger()
+	sub := ledger.SubscribeEvents(0)
+	for range eventSubscriberBuffer + eventBufferSize + 1 {
+		ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+	}
+
+	n := 0
+	for range sub.Events {
+		n++
+	}
+	if n != eventSubscriberBuffer {
+		t.Errorf("Expected a slow subscriber to be dropped after %d events, got %d", eventSubscriberBuffer, n)
+	}
+	sub.Close()
+
+	resumed := ledger.SubscribeEvents(uint64(n))
+	defer resumed.Close()
+	if len(resumed.Backlog) != 1 || resumed.Backlog[0].Type != EventResync || resumed.Backlog[0].ID != eventSubscriberBuffer+eventBufferSize+1 {
+		t.Errorf("Expected a resync once events left the buffer, got %+v", resumed.Backlog)
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+	server := httptest.NewServer(http.HandlerFunc(NewHandler(ledger).EventsHandler))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	ledger.AddTransaction(&Transaction{ID: "a", Amount: 10, Category: "food", Date: date("2024-03-01"), Type: "expense"})
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 4 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) < 4 || lines[0] != "id: 2" || lines[1] != "event: transaction.created" || !strings.Contains(lines[2], `"id":"a"`) {
+		t.Errorf("Expected the created transaction, got %q", lines)
+	}
+}
+
+func TestEventsHandler_CloseEvents(t *testing.T) {
+	ledger := NewLedger()
+	server := httptest.NewServer(http.HandlerFunc(NewHandler(ledger).EventsHandler))
+	defer server.Close()
+	server.Config.RegisterOnShutdown(ledger.CloseEvents)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the open stream not to hold up shutdown, got %v", err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+
+	if _, open := <-ledger.SubscribeEvents(0).Events; open {
+		t.Error("Expected subscriptions after closing to be closed")
+	}
+}
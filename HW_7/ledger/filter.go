@@ -0,0 +1,129 @@
+package ledger
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type TransactionFilter struct {
+	Category string    `query:"category"`
+	Type     string    `query:"type" enum:"income,expense,transfer"`
+	Tag      string    `query:"tag"`
+	From     time.Time `query:"from" format:"date"`
+	To       time.Time `query:"to" format:"date"`
+	Limit    int       `query:"limit"`
+	Offset   int       `query:"offset"`
+}
+
+func ParseTransactionFilter(values url.Values) (TransactionFilter, error) {
+	var f TransactionFilter
+	var err error
+
+	f.Category = values.Get("category")
+	f.Type = values.Get("type")
+	f.Tag = values.Get("tag")
+	if f.Type != "" && f.Type != "income" && f.Type != "expense" && f.Type != "transfer" {
+		return f, errors.New("type must be 'income', 'expense' or 'transfer'")
+	}
+
+	if from := values.Get("from"); from != "" {
+		if f.From, err = time.Parse(dateLayout, from); err != nil {
+			return f, errors.New("invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if f.To, err = time.Parse(dateLayout, to); err != nil {
+			return f, errors.New("invalid to date, use YYYY-MM-DD")
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil || f.Limit < 0 {
+			return f, errors.New("limit must be a non-negative integer")
+		}
+	}
+	if offset := values.Get("offset"); offset != "" {
+		if f.Offset, err = strconv.Atoi(offset); err != nil || f.Offset < 0 {
+			return f, errors.New("offset must be a non-negative integer")
+		}
+	}
+
+	return f, nil
+}
+
+func (f TransactionFilter) Query() url.Values {
+	values := url.Values{}
+	if f.Category != "" {
+		values.Set("category", f.Category)
+	}
+	if f.Type != "" {
+		values.Set("type", f.Type)
+	}
+	if f.Tag != "" {
+		values.Set("tag", f.Tag)
+	}
+	if !f.From.IsZero() {
+		values.Set("from", f.From.Format(dateLayout))
+	}
+	if !f.To.IsZero() {
+		values.Set("to", f.To.Format(dateLayout))
+	}
+	if f.Limit > 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		values.Set("offset", strconv.Itoa(f.Offset))
+	}
+	return values
+}
+
+// Match reports whether tx passes the filter. To is inclusive of the whole day.
+func (f TransactionFilter) Match(tx *Transaction) bool {
+	if f.Category != "" && !tx.hasCategory(f.Category) {
+		return false
+	}
+	if f.Type != "" && tx.Type != f.Type {
+		return false
+	}
+	if f.Tag != "" && !tx.hasTag(f.Tag) {
+		return false
+	}
+	if !f.From.IsZero() && tx.Date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !tx.Date.Before(f.To.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+func (l *Ledger) FilterTransactions(f TransactionFilter) []*Transaction {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	candidates := l.Transactions
+	if f.Tag != "" {
+		candidates = l.tags[f.Tag]
+	}
+
+	result := make([]*Transaction, 0)
+	skipped := 0
+	for _, tx := range candidates {
+		if !f.Match(tx) {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		result = append(result, tx)
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result
+}
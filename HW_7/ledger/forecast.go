@@ -0,0 +1,383 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+const (
+	// forecastHistory is how many previous periods feed the seasonal method
+	// and the confidence bounds.
+	forecastHistory = 6
+	// forecastZ scales the spread of past periods into an 80% interval.
+	forecastZ = 1.28
+	// forecastDefaultSpread is the relative spread assumed without history.
+	forecastDefaultSpread = 0.5
+)
+
+type RecurringTransaction struct {
+	Description string
+	Amount      float64
+	// Date is when the transaction is expected in the forecast period.
+	Date time.Time
+	Paid bool
+}
+
+type BudgetForecast struct {
+	Category    string
+	PeriodStart time.Time
+	AsOf        time.Time
+	Limit       float64
+	Spent       float64
+
+	// RunRate extrapolates this period's discretionary spending linearly.
+	RunRate float64
+	// Seasonal scales it by the share previous periods had spent by the same
+	// point; zero without history.
+	Seasonal  float64
+	Recurring []RecurringTransaction
+	// Scheduled lists the pending transactions of the period; they replace
+	// any recurring transaction they match.
+	Scheduled []RecurringTransaction
+
+	Projected float64
+	Low       float64
+	High      float64
+	// LimitHitDate is when spending is expected to cross the limit, or zero.
+	LimitHitDate time.Time
+}
+
+// ForecastBudget projects the category's spending to the end of the month
+// containing asOf. Transactions repeating with the same description and amount
+// in each of the two previous months are treated as known recurring spend and
+// kept out of the run-rate, as are pending transactions, which are counted on
+// their dates. Posted transactions dated after asOf are ignored, so a past
+// asOf forecasts from what was known then.
+func (l *Ledger) ForecastBudget(category string, asOf time.Time) (*BudgetForecast, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	budget, exists := l.Budgets[category]
+	if !exists {
+		return nil, ErrBudgetNotFound
+	}
+
+	start := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, asOf.Location())
+	totalDays := daysIn(start)
+	elapsedDays := asOf.Day()
+	remainingDays := totalDays - elapsedDays
+
+	byPeriod := make(map[int][]*Transaction)
+	var scheduled []RecurringTransaction
+	for _, tx := range l.Transactions {
+		if tx.Type != "expense" {
+			continue
+		}
+		if tx = tx.inCategory(category); tx == nil {
+			continue
+		}
+		k := periodsBefore(start, tx.Date)
+		if tx.Status == StatusPending {
+			if k == 0 {
+				scheduled = append(scheduled, RecurringTransaction{Description: tx.Description, Amount: tx.Amount, Date: tx.Date})
+			}
+			continue
+		}
+		if tx.Date.After(asOf) {
+			continue
+		}
+		if k >= 0 && k <= forecastHistory {
+			byPeriod[k] = append(byPeriod[k], tx)
+		}
+	}
+
+	f := &BudgetForecast{
+		Category:    category,
+		PeriodStart: start,
+		AsOf:        asOf,
+		Limit:       budget.Limit,
+		Recurring:   detectRecurring(byPeriod, start),
+		Scheduled:   scheduled,
+	}
+
+	recurring := make(map[string]bool)
+	var recurringTotal, pending float64
+	for _, rt := range scheduled {
+		recurring[recurringKey(rt.Description, rt.Amount)] = true
+		recurringTotal += rt.Amount
+		pending += rt.Amount
+	}
+	f.Recurring = slices.DeleteFunc(f.Recurring, func(rt RecurringTransaction) bool {
+		return recurring[recurringKey(rt.Description, rt.Amount)]
+	})
+	for _, rt := range f.Recurring {
+		recurring[recurringKey(rt.Description, rt.Amount)] = true
+		recurringTotal += rt.Amount
+		if !rt.Paid {
+			pending += rt.Amount
+		}
+	}
+	discretionary := func(tx *Transaction) bool {
+		return !recurring[recurringKey(tx.Description, tx.Amount)]
+	}
+
+	var current float64
+	for _, tx := range byPeriod[0] {
+		f.Spent += tx.Amount
+		if discretionary(tx) {
+			current += tx.Amount
+		}
+	}
+
+	f.RunRate = current/float64(elapsedDays)*float64(totalDays) + recurringTotal
+
+	// Seasonal: how much of each past period's discretionary total had been
+	// spent by the same relative day.
+	fraction := float64(elapsedDays) / float64(totalDays)
+	var shares, totals []float64
+	for k := 1; k <= forecastHistory; k++ {
+		txs, exists := byPeriod[k]
+		if !exists {
+			continue
+		}
+		periodStart := start.AddDate(0, -k, 0)
+		cutoff := int(math.Round(fraction * float64(daysIn(periodStart))))
+
+		var total, byCutoff float64
+		for _, tx := range txs {
+			if !discretionary(tx) {
+				continue
+			}
+			total += tx.Amount
+			if tx.Date.Day() <= cutoff {
+				byCutoff += tx.Amount
+			}
+		}
+		if total > 0 {
+			shares = append(shares, byCutoff/total)
+			totals = append(totals, total)
+		}
+	}
+
+	f.Projected = f.RunRate
+	if len(totals) > 0 {
+		if share := mean(shares); share > 0 && current > 0 {
+			f.Seasonal = current/share + recurringTotal
+		} else {
+			f.Seasonal = math.Max(current, mean(totals)) + recurringTotal
+		}
+		f.Projected = (f.RunRate + f.Seasonal) / 2
+	}
+
+	// The interval only widens the part of the projection not yet spent or
+	// known, by the spread of past period totals.
+	committed := f.Spent + pending
+	f.Projected = math.Max(f.Projected, committed)
+	rest := f.Projected - committed
+	spread := forecastDefaultSpread
+	if len(totals) >= 2 {
+		spread = stddev(totals) / mean(totals)
+	}
+	f.Low = committed + rest*math.Max(0, 1-forecastZ*spread)
+	f.High = committed + rest*(1+forecastZ*spread)
+
+	f.LimitHitDate = limitHitDate(f, rest, remainingDays)
+	return f, nil
+}
+
+// limitHitDate walks the remaining days, spreading the unknown spend evenly
+// and adding unpaid recurring and scheduled transactions on their dates.
+func limitHitDate(f *BudgetForecast, rest float64, remainingDays int) time.Time {
+	if f.Spent >= f.Limit {
+		return f.AsOf
+	}
+
+	cumulative := f.Spent
+	day := f.AsOf
+	for i := 0; i < remainingDays; i++ {
+		day = day.AddDate(0, 0, 1)
+		cumulative += rest / float64(remainingDays)
+		for _, rt := range slices.Concat(f.Recurring, f.Scheduled) {
+			if !rt.Paid && sameDay(rt.Date, day) {
+				cumulative += rt.Amount
+			}
+		}
+		if cumulative > f.Limit {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+func detectRecurring(byPeriod map[int][]*Transaction, start time.Time) []RecurringTransaction {
+	seen := make(map[string]bool)
+	for _, tx := range byPeriod[2] {
+		seen[recurringKey(tx.Description, tx.Amount)] = true
+	}
+	paid := make(map[string]bool)
+	for _, tx := range byPeriod[0] {
+		paid[recurringKey(tx.Description, tx.Amount)] = true
+	}
+
+	var recurring []RecurringTransaction
+	for _, tx := range byPeriod[1] {
+		key := recurringKey(tx.Description, tx.Amount)
+		if tx.Description == "" || !seen[key] {
+			continue
+		}
+		seen[key] = false
+
+		day := min(tx.Date.Day(), daysIn(start))
+		recurring = append(recurring, RecurringTransaction{
+			Description: tx.Description,
+			Amount:      tx.Amount,
+			Date:        start.AddDate(0, 0, day-1),
+			Paid:        paid[key],
+		})
+	}
+	return recurring
+}
+
+func recurringKey(description string, amount float64) string {
+	return strings.ToLower(strings.TrimSpace(description)) + "|" + strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// periodsBefore returns how many months date lies before the month starting
+// at start: 0 for the same month, -1 for later months.
+func periodsBefore(start, date time.Time) int {
+	months := (start.Year()-date.Year())*12 + int(start.Month()) - int(date.Month())
+	if months < 0 {
+		return -1
+	}
+	return months
+}
+
+func daysIn(periodStart time.Time) int {
+	return periodStart.AddDate(0, 1, -1).Day()
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stddev(values []float64) float64 {
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+type RecurringTransactionResponse struct {
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Date        string  `json:"date" format:"date"`
+	Paid        bool    `json:"paid"`
+}
+
+func newRecurringTransactionResponse(rt RecurringTransaction) RecurringTransactionResponse {
+	return RecurringTransactionResponse{
+		Description: rt.Description,
+		Amount:      rt.Amount,
+		Date:        rt.Date.Format(dateLayout),
+		Paid:        rt.Paid,
+	}
+}
+
+type BudgetForecastResponse struct {
+	Category     string                         `json:"category"`
+	Period       string                         `json:"period"`
+	AsOf         string                         `json:"as_of" format:"date"`
+	Limit        float64                        `json:"limit"`
+	Spent        float64                        `json:"spent"`
+	Projected    float64                        `json:"projected"`
+	Low          float64                        `json:"low"`
+	High         float64                        `json:"high"`
+	RunRate      float64                        `json:"run_rate"`
+	Seasonal     float64                        `json:"seasonal,omitempty"`
+	Recurring    []RecurringTransactionResponse `json:"recurring"`
+	Scheduled    []RecurringTransactionResponse `json:"scheduled"`
+	LimitHitDate string                         `json:"limit_hit_date,omitempty" format:"date"`
+}
+
+type ForecastQuery struct {
+	AsOf time.Time `query:"as_of" format:"date"`
+}
+
+func (h *Handler) ForecastBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f, err := h.ledger.ForecastBudget(r.PathValue("category"), asOf)
+	if errors.Is(err, ErrBudgetNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := BudgetForecastResponse{
+		Category:  f.Category,
+		Period:    periodKey(f.PeriodStart),
+		AsOf:      f.AsOf.Format(dateLayout),
+		Limit:     f.Limit,
+		Spent:     f.Spent,
+		Projected: f.Projected,
+		Low:       f.Low,
+		High:      f.High,
+		RunRate:   f.RunRate,
+		Seasonal:  f.Seasonal,
+		Recurring: make([]RecurringTransactionResponse, len(f.Recurring)),
+		Scheduled: make([]RecurringTransactionResponse, len(f.Scheduled)),
+	}
+	for i, rt := range f.Recurring {
+		response.Recurring[i] = newRecurringTransactionResponse(rt)
+	}
+	for i, rt := range f.Scheduled {
+		response.Scheduled[i] = newRecurringTransactionResponse(rt)
+	}
+	if !f.LimitHitDate.IsZero() {
+		response.LimitHitDate = f.LimitHitDate.Format(dateLayout)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseAsOf reads the optional as_of query parameter, defaulting to today.
+func parseAsOf(r *http.Request) (time.Time, error) {
+	raw := r.URL.Query().Get("as_of")
+	if raw == "" {
+		return today(), nil
+	}
+	asOf, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, errors.New("invalid as_of date, use YYYY-MM-DD")
+	}
+	return asOf, nil
+}
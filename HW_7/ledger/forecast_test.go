@@ -0,0 +1,202 @@
+package ledger
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func seedForecastLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 1000})
+
+	txs := []struct {
+		date        string
+		amount      float64
+		description string
+	}{
+		{"2023-11-03", 100, ""},
+		{"2023-11-05", 15, "Streaming"},
+		{"2023-11-20", 200, ""},
+		{"2023-11-20", 400, "Meal plan"},
+		{"2023-12-05", 15, "Streaming"},
+		{"2023-12-10", 200, ""},
+		{"2023-12-20", 400, "Meal plan"},
+		{"2023-12-25", 300, ""},
+		{"2024-01-03", 100, ""},
+		{"2024-01-05", 15, "streaming "},
+		{"2024-01-12", 200, ""},
+	}
+	for i, tx := range txs {
+		// Budgets are enforced over all time, so seed past the limit directly.
+		ledger.mu.Lock()
+		ledger.Transactions = append(ledger.Transactions, &Transaction{
+			ID:          string(rune('a' + i)),
+			Amount:      tx.amount,
+			Category:    "food",
+			Description: tx.description,
+			Date:        date(tx.date),
+			Type:        "expense",
+		})
+		ledger.spending.add(ledger.Transactions[i])
+		ledger.mu.Unlock()
+	}
+	return ledger
+}
+
+func TestLedger_ForecastBudget(t *testing.T) {
+	ledger := seedForecastLedger(t)
+
+	f, err := ledger.ForecastBudget("food", date("2024-01-15"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("recurring transactions", func(t *testing.T) {
+		if len(f.Recurring) != 2 {
+			t.Fatalf("Expected 2 recurring transactions, got %+v", f.Recurring)
+		}
+		for _, rt := range f.Recurring {
+			switch rt.Description {
+			case "Streaming":
+				if !rt.Paid {
+					t.Error("Expected streaming to be paid this month")
+				}
+			case "Meal plan":
+				if rt.Paid || !rt.Date.Equal(date("2024-01-20")) {
+					t.Errorf("Expected meal plan pending on 2024-01-20, got %+v", rt)
+				}
+			default:
+				t.Errorf("Unexpected recurring transaction %+v", rt)
+			}
+		}
+	})
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"spent", f.Spent, 315},
+		{"run rate", f.RunRate, 300.0/15*31 + 415},
+		{"seasonal", f.Seasonal, 300/((100.0/300+200.0/500)/2) + 415},
+		{"projected", f.Projected, 1134.09},
+		{"low", f.Low, 944.43},
+		{"high", f.High, 1323.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !approx(tt.got, tt.want) {
+				t.Errorf("Expected %.2f, got %.2f", tt.want, tt.got)
+			}
+		})
+	}
+
+	t.Run("limit hit date", func(t *testing.T) {
+		if !f.LimitHitDate.Equal(date("2024-01-26")) {
+			t.Errorf("Expected limit to be hit on 2024-01-26, got %v", f.LimitHitDate)
+		}
+	})
+}
+
+func TestLedger_ForecastBudget_PastAsOf(t *testing.T) {
+	ledger := seedForecastLedger(t)
+
+	f, err := ledger.ForecastBudget("food", date("2024-01-07"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !approx(f.Spent, 115) {
+		t.Errorf("Expected later spending to be ignored, got %.2f spent", f.Spent)
+	}
+	if want := 100.0/7*31 + 415; !approx(f.RunRate, want) {
+		t.Errorf("Expected run rate %.2f, got %.2f", want, f.RunRate)
+	}
+	if !f.LimitHitDate.IsZero() {
+		t.Errorf("Expected limit to hold, got %v", f.LimitHitDate)
+	}
+}
+
+func TestLedger_ForecastBudget_NoHistory(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 1000})
+	ledger.AddTransaction(&Transaction{ID: "1", Amount: 100, Category: "food", Date: date("2024-02-05"), Type: "expense"})
+
+	f, err := ledger.ForecastBudget("food", date("2024-02-10"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if f.Seasonal != 0 {
+		t.Errorf("Expected no seasonal projection without history, got %v", f.Seasonal)
+	}
+	if !approx(f.Projected, 290) {
+		t.Errorf("Expected run-rate projection 290, got %v", f.Projected)
+	}
+	if !approx(f.Low, 100+190*(1-forecastZ*forecastDefaultSpread)) || !approx(f.High, 100+190*(1+forecastZ*forecastDefaultSpread)) {
+		t.Errorf("Expected default spread bounds, got %v-%v", f.Low, f.High)
+	}
+	if !f.LimitHitDate.IsZero() {
+		t.Errorf("Expected limit to hold, got %v", f.LimitHitDate)
+	}
+
+	if _, err := ledger.ForecastBudget("rent", date("2024-02-10")); err != ErrBudgetNotFound {
+		t.Errorf("Expected %v, got %v", ErrBudgetNotFound, err)
+	}
+}
+
+func TestForecastBudgetHandler(t *testing.T) {
+	handler := NewHandler(seedForecastLedger(t))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/budgets/{category}/forecast", handler.ForecastBudgetHandler)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"forecast", "/api/budgets/food/forecast?as_of=2024-01-15", http.StatusOK},
+		{"invalid date", "/api/budgets/food/forecast?as_of=15-01-2024", http.StatusBadRequest},
+		{"unknown budget", "/api/budgets/rent/forecast", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response BudgetForecastResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Period != "2024-01" || response.LimitHitDate != "2024-01-26" || len(response.Recurring) != 2 {
+				t.Errorf("Unexpected forecast %+v", response)
+			}
+		})
+	}
+}
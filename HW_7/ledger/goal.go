@@ -0,0 +1,377 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrGoalNotFound = errors.New("goal not found")
+
+const (
+	GoalOnTrack  = "on_track"
+	GoalBehind   = "behind"
+	GoalAchieved = "achieved"
+	GoalOverdue  = "overdue"
+)
+
+// Goal is a savings target funded by income and transfers recorded in
+// Category between Start and Deadline.
+type Goal struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Target   float64   `json:"target"`
+	Category string    `json:"category"`
+	Start    time.Time `json:"start"`
+	Deadline time.Time `json:"deadline"`
+}
+
+type GoalProgress struct {
+	Goal      *Goal
+	AsOf      time.Time
+	Saved     float64
+	Remaining float64
+	// Expected is what a steady plan from Start to Deadline would have saved
+	// by AsOf.
+	Expected float64
+	// MonthsLeft counts the monthly contributions still possible, including
+	// the month containing AsOf.
+	MonthsLeft      int
+	RequiredMonthly float64
+	Status          string
+}
+
+func (l *Ledger) AddGoal(g *Goal) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.Goals[g.ID] = g
+	return nil
+}
+
+func (l *Ledger) UpdateGoal(g *Goal) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.Goals[g.ID]; !exists {
+		return ErrGoalNotFound
+	}
+	l.Goals[g.ID] = g
+	return nil
+}
+
+func (l *Ledger) DeleteGoal(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.Goals[id]; !exists {
+		return ErrGoalNotFound
+	}
+	delete(l.Goals, id)
+	return nil
+}
+
+func (l *Ledger) GetGoal(id string) (*Goal, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	goal, exists := l.Goals[id]
+	return goal, exists
+}
+
+// ListGoals returns goals ordered by deadline.
+func (l *Ledger) ListGoals() []*Goal {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.sortedGoals()
+}
+
+func (l *Ledger) sortedGoals() []*Goal {
+	goals := make([]*Goal, 0, len(l.Goals))
+	for _, goal := range l.Goals {
+		goals = append(goals, goal)
+	}
+	sort.Slice(goals, func(i, j int) bool {
+		if !goals[i].Deadline.Equal(goals[j].Deadline) {
+			return goals[i].Deadline.Before(goals[j].Deadline)
+		}
+		return goals[i].ID < goals[j].ID
+	})
+	return goals
+}
+
+func (l *Ledger) GoalProgress(id string, asOf time.Time) (*GoalProgress, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	goal, exists := l.Goals[id]
+	if !exists {
+		return nil, ErrGoalNotFound
+	}
+	return l.goalProgress(goal, asOf), nil
+}
+
+// ListGoalProgress evaluates every goal as of the given date, ordered by
+// deadline.
+func (l *Ledger) ListGoalProgress(asOf time.Time) []*GoalProgress {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	goals := l.sortedGoals()
+	progress := make([]*GoalProgress, len(goals))
+	for i, goal := range goals {
+		progress[i] = l.goalProgress(goal, asOf)
+	}
+	return progress
+}
+
+func (l *Ledger) goalProgress(g *Goal, asOf time.Time) *GoalProgress {
+	p := &GoalProgress{Goal: g, AsOf: asOf}
+
+	for _, tx := range l.Transactions {
+		if tx.Type != "income" && tx.Type != "transfer" {
+			continue
+		}
+		if tx = tx.inCategory(g.Category); tx == nil {
+			continue
+		}
+		if tx.Date.Before(g.Start) || tx.Date.After(asOf) {
+			continue
+		}
+		p.Saved += tx.Amount
+	}
+	p.Remaining = math.Max(0, g.Target-p.Saved)
+
+	total := g.Deadline.Sub(g.Start)
+	elapsed := min(max(asOf.Sub(g.Start), 0), total)
+	p.Expected = g.Target * float64(elapsed) / float64(total)
+
+	deadlineMonth := time.Date(g.Deadline.Year(), g.Deadline.Month(), 1, 0, 0, 0, 0, g.Deadline.Location())
+	if !asOf.After(g.Deadline) {
+		p.MonthsLeft = periodsBefore(deadlineMonth, asOf) + 1
+	}
+	if p.MonthsLeft > 0 {
+		p.RequiredMonthly = p.Remaining / float64(p.MonthsLeft)
+	}
+
+	switch {
+	case p.Remaining == 0:
+		p.Status = GoalAchieved
+	case p.MonthsLeft == 0:
+		p.Status = GoalOverdue
+	case p.Saved >= p.Expected:
+		p.Status = GoalOnTrack
+	default:
+		p.Status = GoalBehind
+	}
+	return p
+}
+
+type CreateGoalRequest struct {
+	Name     string  `json:"name"`
+	Target   float64 `json:"target"`
+	Category string  `json:"category"`
+	Start    string  `json:"start,omitempty" format:"date"`
+	Deadline string  `json:"deadline" format:"date"`
+}
+
+type GoalResponse struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Target          float64 `json:"target"`
+	Category        string  `json:"category"`
+	Start           string  `json:"start" format:"date"`
+	Deadline        string  `json:"deadline" format:"date"`
+	AsOf            string  `json:"as_of" format:"date"`
+	Saved           float64 `json:"saved"`
+	Remaining       float64 `json:"remaining"`
+	Expected        float64 `json:"expected"`
+	MonthsLeft      int     `json:"months_left"`
+	RequiredMonthly float64 `json:"required_monthly"`
+	Status          string  `json:"status" enum:"on_track,behind,achieved,overdue"`
+}
+
+type GoalQuery struct {
+	AsOf time.Time `query:"as_of" format:"date"`
+}
+
+func newGoalResponse(p *GoalProgress) GoalResponse {
+	return GoalResponse{
+		ID:              p.Goal.ID,
+		Name:            p.Goal.Name,
+		Target:          p.Goal.Target,
+		Category:        p.Goal.Category,
+		Start:           p.Goal.Start.Format(dateLayout),
+		Deadline:        p.Goal.Deadline.Format(dateLayout),
+		AsOf:            p.AsOf.Format(dateLayout),
+		Saved:           p.Saved,
+		Remaining:       p.Remaining,
+		Expected:        p.Expected,
+		MonthsLeft:      p.MonthsLeft,
+		RequiredMonthly: p.RequiredMonthly,
+		Status:          p.Status,
+	}
+}
+
+// decodeGoal reads a CreateGoalRequest into a goal with the given ID, using
+// start when the request omits it.
+func decodeGoal(r *http.Request, id string, start time.Time) (*Goal, error) {
+	var req CreateGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.New("invalid JSON format")
+	}
+
+	deadline, err := time.Parse(dateLayout, req.Deadline)
+	if err != nil {
+		return nil, errors.New("invalid deadline, use YYYY-MM-DD")
+	}
+	if req.Start != "" {
+		if start, err = time.Parse(dateLayout, req.Start); err != nil {
+			return nil, errors.New("invalid start, use YYYY-MM-DD")
+		}
+	}
+
+	return &Goal{
+		ID:       id,
+		Name:     req.Name,
+		Target:   req.Target,
+		Category: req.Category,
+		Start:    start,
+		Deadline: deadline,
+	}, nil
+}
+
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (h *Handler) CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	goal, err := decodeGoal(r, uuid.New().String(), today())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.ledger.AddGoal(goal); err != nil {
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
+		return
+	}
+
+	progress, err := h.ledger.GoalProgress(goal.ID, today())
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, newGoalResponse(progress))
+}
+
+func (h *Handler) ListGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	progress := h.ledger.ListGoalProgress(asOf)
+	response := make([]GoalResponse, len(progress))
+	for i, p := range progress {
+		response[i] = newGoalResponse(p)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) GetGoalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	progress, err := h.ledger.GoalProgress(r.PathValue("id"), asOf)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newGoalResponse(progress))
+}
+
+func (h *Handler) UpdateGoalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := r.PathValue("id")
+	existing, exists := h.ledger.GetGoal(id)
+	if !exists {
+		writeError(w, http.StatusNotFound, ErrGoalNotFound.Error())
+		return
+	}
+
+	goal, err := decodeGoal(r, id, existing.Start)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.ledger.UpdateGoal(goal); errors.Is(err, ErrGoalNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
+		return
+	}
+
+	progress, err := h.ledger.GoalProgress(id, today())
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, newGoalResponse(progress))
+}
+
+func (h *Handler) DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := h.ledger.DeleteGoal(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,205 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func seedGoalLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	ledger := NewLedger()
+	txs := []*Transaction{
+		{ID: "1", Amount: 500, Category: "savings", Date: date("2023-12-20"), Type: "income"},
+		{ID: "2", Amount: 300, Category: "savings", Date: date("2024-01-10"), Type: "income"},
+		{ID: "3", Amount: 300, Category: "savings", Date: date("2024-02-10"), Type: "income"},
+		{ID: "4", Amount: 3000, Category: "salary", Date: date("2024-02-01"), Type: "income"},
+		{ID: "5", Amount: 100, Category: "savings", Date: date("2024-02-12"), Type: "expense"},
+		{ID: "6", Amount: 1200, Category: "rent", Date: date("2024-02-03"), Type: "expense"},
+	}
+	mustAdd(t, ledger, txs...)
+
+	ledger.AddGoal(&Goal{ID: "car", Name: "Car", Target: 2400, Category: "savings", Start: date("2024-01-01"), Deadline: date("2024-12-31")})
+	return ledger
+}
+
+func TestLedger_GoalProgress(t *testing.T) {
+	ledger := seedGoalLedger(t)
+
+	tests := []struct {
+		name       string
+		asOf       string
+		saved      float64
+		monthsLeft int
+		required   float64
+		status     string
+	}{
+		{"income before start is ignored", "2024-01-31", 300, 12, 175, GoalOnTrack},
+		{"contributions so far", "2024-02-15", 600, 11, 163.64, GoalOnTrack},
+		{"falling behind", "2024-06-30", 600, 7, 257.14, GoalBehind},
+		{"last month", "2024-12-01", 600, 1, 1800, GoalBehind},
+		{"after deadline", "2025-01-05", 600, 0, 0, GoalOverdue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ledger.GoalProgress("car", date(tt.asOf))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !approx(p.Saved, tt.saved) {
+				t.Errorf("Expected saved %.2f, got %.2f", tt.saved, p.Saved)
+			}
+			if p.MonthsLeft != tt.monthsLeft {
+				t.Errorf("Expected %d months left, got %d", tt.monthsLeft, p.MonthsLeft)
+			}
+			if !approx(p.RequiredMonthly, tt.required) {
+				t.Errorf("Expected required monthly %.2f, got %.2f", tt.required, p.RequiredMonthly)
+			}
+			if p.Status != tt.status {
+				t.Errorf("Expected status %s, got %s", tt.status, p.Status)
+			}
+		})
+	}
+
+	t.Run("transfers count", func(t *testing.T) {
+		mustAdd(t, ledger, &Transaction{ID: "7", Amount: 200, Category: "savings", Date: date("2024-03-05"), Type: "transfer"})
+		p, _ := ledger.GoalProgress("car", date("2024-03-31"))
+		if !approx(p.Saved, 800) {
+			t.Errorf("Expected saved 800, got %.2f", p.Saved)
+		}
+	})
+
+	t.Run("achieved", func(t *testing.T) {
+		ledger.AddGoal(&Goal{ID: "phone", Name: "Phone", Target: 500, Category: "savings", Start: date("2024-01-01"), Deadline: date("2024-03-01")})
+		p, _ := ledger.GoalProgress("phone", date("2024-02-15"))
+		if p.Status != GoalAchieved || p.Remaining != 0 || p.RequiredMonthly != 0 {
+			t.Errorf("Expected achieved goal with nothing remaining, got %+v", p)
+		}
+	})
+
+	if _, err := ledger.GoalProgress("boat", date("2024-02-15")); err != ErrGoalNotFound {
+		t.Errorf("Expected %v, got %v", ErrGoalNotFound, err)
+	}
+}
+
+func TestGoal_Validate(t *testing.T) {
+	valid := Goal{Name: "Car", Target: 100, Category: "savings", Start: date("2024-01-01"), Deadline: date("2024-06-01")}
+
+	tests := []struct {
+		name   string
+		modify func(g *Goal)
+	}{
+		{"empty name", func(g *Goal) { g.Name = "" }},
+		{"zero target", func(g *Goal) { g.Target = 0 }},
+		{"empty category", func(g *Goal) { g.Category = "" }},
+		{"deadline before start", func(g *Goal) { g.Deadline = date("2023-12-01") }},
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid goal, got %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := valid
+			tt.modify(&g)
+			if err := g.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestGoalHandlers(t *testing.T) {
+	handler := NewHandler(seedGoalLedger(t))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/goals", handler.CreateGoalHandler)
+	mux.HandleFunc("GET /api/goals", handler.ListGoalsHandler)
+	mux.HandleFunc("GET /api/goals/{id}", handler.GetGoalHandler)
+	mux.HandleFunc("PUT /api/goals/{id}", handler.UpdateGoalHandler)
+	mux.HandleFunc("DELETE /api/goals/{id}", handler.DeleteGoalHandler)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rr
+	}
+
+	rr := do("POST", "/api/goals", `{"name":"Trip","target":900,"category":"savings","start":"2024-01-01","deadline":"2024-09-30"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var created GoalResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"invalid deadline", "POST", "/api/goals", `{"name":"Trip","target":900,"category":"savings","deadline":"30-09-2024"}`, http.StatusBadRequest},
+		{"invalid goal", "POST", "/api/goals", `{"name":"Trip","target":-5,"category":"savings","deadline":"2030-09-30"}`, http.StatusBadRequest},
+		{"get", "GET", "/api/goals/" + created.ID + "?as_of=2024-02-15", "", http.StatusOK},
+		{"get unknown", "GET", "/api/goals/boat", "", http.StatusNotFound},
+		{"update", "PUT", "/api/goals/" + created.ID, `{"name":"Trip","target":1200,"category":"savings","deadline":"2024-12-31"}`, http.StatusOK},
+		{"update unknown", "PUT", "/api/goals/boat", `{"name":"Boat","target":1200,"category":"savings","deadline":"2024-12-31"}`, http.StatusNotFound},
+		{"delete", "DELETE", "/api/goals/car", "", http.StatusNoContent},
+		{"delete again", "DELETE", "/api/goals/car", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("list after changes", func(t *testing.T) {
+		rr := do("GET", "/api/goals?as_of=2024-02-15", "")
+		var goals []GoalResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &goals); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(goals) != 1 {
+			t.Fatalf("Expected 1 goal, got %d", len(goals))
+		}
+		got := goals[0]
+		if got.Target != 1200 || got.Start != "2024-01-01" || got.Saved != 600 || got.MonthsLeft != 11 {
+			t.Errorf("Expected updated goal keeping its start, got %+v", got)
+		}
+	})
+}
+
+func TestSummaryHandler(t *testing.T) {
+	ledger := seedGoalLedger(t)
+	ledger.SetBudget(&Budget{Category: "rent", Limit: 1500})
+	handler := NewHandler(ledger)
+
+	rr := httptest.NewRecorder()
+	handler.SummaryHandler(rr, httptest.NewRequest("GET", "/api/summary?as_of=2024-02-15", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var summary SummaryResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if summary.Period != "2024-02" || summary.Income != 3300 || summary.Expenses != 1300 || summary.Net != 2000 {
+		t.Errorf("Unexpected totals %+v", summary)
+	}
+	if len(summary.Budgets) != 1 || summary.Budgets[0].Spent != 1200 {
+		t.Errorf("Expected rent budget with 1200 spent, got %+v", summary.Budgets)
+	}
+	if len(summary.Goals) != 1 || summary.Goals[0].Status != GoalOnTrack {
+		t.Errorf("Expected car goal on track, got %+v", summary.Goals)
+	}
+}
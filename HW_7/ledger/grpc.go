@@ -0,0 +1,198 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jukov801/Golang_MIPT/HW_6/ledgerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type GRPCServer struct {
+	ledgerpb.UnimplementedLedgerServiceServer
+	ledger *Ledger
+}
+
+func NewGRPCServer(ledger *Ledger) *GRPCServer {
+	return &GRPCServer{ledger: ledger}
+}
+
+func (s *GRPCServer) Register(server *grpc.Server) {
+	ledgerpb.RegisterLedgerServiceServer(server, s)
+}
+
+func (s *GRPCServer) CreateTransaction(ctx context.Context, req *ledgerpb.CreateTransactionRequest) (*ledgerpb.Transaction, error) {
+	tx := &Transaction{
+		ID:          uuid.New().String(),
+		Amount:      req.GetAmount(),
+		Category:    req.GetCategory(),
+		Description: req.GetDescription(),
+		Type:        req.GetType(),
+		Tags:        req.GetTags(),
+		Metadata:    req.GetMetadata(),
+	}
+	if req.GetDate() != nil {
+		tx.Date = req.GetDate().AsTime()
+	}
+	for _, line := range req.GetSplits() {
+		tx.Splits = append(tx.Splits, Split{Category: line.GetCategory(), Amount: line.GetAmount(), Note: line.GetNote()})
+	}
+	if sharing := req.GetSharing(); sharing != nil {
+		tx.Sharing = &Sharing{PaidBy: sharing.GetPaidBy(), Method: sharing.GetMethod()}
+		for _, p := range sharing.GetParticipants() {
+			tx.Sharing.Participants = append(tx.Sharing.Participants, Participant{Member: p.GetMember(), Shares: p.GetShares(), Amount: p.GetAmount()})
+		}
+	}
+
+	if err := s.ledger.AddTransactionContext(ctx, tx); err != nil {
+		return nil, grpcError(err)
+	}
+
+	return transactionToProto(tx), nil
+}
+
+func (s *GRPCServer) ListTransactions(req *ledgerpb.ListTransactionsRequest, stream grpc.ServerStreamingServer[ledgerpb.Transaction]) error {
+	filter := TransactionFilter{
+		Category: req.GetCategory(),
+		Type:     req.GetType(),
+		Tag:      req.GetTag(),
+		Limit:    int(req.GetLimit()),
+		Offset:   int(req.GetOffset()),
+	}
+	if req.GetFrom() != nil {
+		filter.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		filter.To = req.GetTo().AsTime()
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return status.Error(codes.InvalidArgument, "limit and offset must be non-negative")
+	}
+
+	for _, tx := range s.ledger.FilterTransactions(filter) {
+		if err := stream.Send(transactionToProto(tx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GRPCServer) SetBudget(ctx context.Context, req *ledgerpb.SetBudgetRequest) (*ledgerpb.Budget, error) {
+	budget := &Budget{
+		Category: req.GetCategory(),
+		Limit:    req.GetLimit(),
+	}
+
+	if err := s.ledger.SetBudget(budget); err != nil {
+		return nil, grpcError(err)
+	}
+
+	return s.budgetToProto(budget), nil
+}
+
+func (s *GRPCServer) ListBudgets(ctx context.Context, req *ledgerpb.ListBudgetsRequest) (*ledgerpb.ListBudgetsResponse, error) {
+	budgets := s.ledger.ListBudgets()
+	response := &ledgerpb.ListBudgetsResponse{
+		Budgets: make([]*ledgerpb.Budget, len(budgets)),
+	}
+
+	for i, budget := range budgets {
+		response.Budgets[i] = s.budgetToProto(budget)
+	}
+
+	return response, nil
+}
+
+func (s *GRPCServer) WatchBudgets(req *ledgerpb.WatchBudgetsRequest, stream grpc.ServerStreamingServer[ledgerpb.Budget]) error {
+	watched := make(map[string]bool, len(req.GetCategories()))
+	for _, category := range req.GetCategories() {
+		watched[category] = true
+	}
+	matches := func(category string) bool {
+		return len(watched) == 0 || watched[category]
+	}
+
+	// Subscribe before taking the snapshot so no change slips in between.
+	updates, stop := s.ledger.WatchBudgets()
+	defer stop()
+
+	for _, budget := range s.ledger.ListBudgets() {
+		if !matches(budget.Category) {
+			continue
+		}
+		if err := stream.Send(s.budgetToProto(budget)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case category := <-updates:
+			if !matches(category) {
+				continue
+			}
+			budget, exists := s.ledger.GetBudget(category)
+			if !exists {
+				continue
+			}
+			if err := stream.Send(s.budgetToProto(budget)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *GRPCServer) budgetToProto(budget *Budget) *ledgerpb.Budget {
+	spent := s.ledger.GetCategorySpending(budget.Category)
+	committed := s.ledger.GetCategoryCommitted(budget.Category)
+	return &ledgerpb.Budget{
+		Category:  budget.Category,
+		Limit:     budget.Limit,
+		Spent:     spent,
+		Committed: committed,
+		Available: budget.Limit - spent - committed,
+	}
+}
+
+func transactionToProto(tx *Transaction) *ledgerpb.Transaction {
+	msg := &ledgerpb.Transaction{
+		Id:          tx.ID,
+		Amount:      tx.Amount,
+		Category:    tx.Category,
+		Description: tx.Description,
+		Date:        timestamppb.New(tx.Date),
+		Type:        tx.Type,
+		Tags:        tx.Tags,
+		Metadata:    tx.Metadata,
+		Status:      tx.Status,
+	}
+	for _, line := range tx.Splits {
+		msg.Splits = append(msg.Splits, &ledgerpb.Split{Category: line.Category, Amount: line.Amount, Note: line.Note})
+	}
+	if tx.Sharing != nil {
+		msg.Sharing = &ledgerpb.Sharing{PaidBy: tx.Sharing.PaidBy, Method: tx.Sharing.Method}
+		for _, p := range tx.Sharing.Participants {
+			msg.Sharing.Participants = append(msg.Sharing.Participants, &ledgerpb.Participant{Member: p.Member, Shares: p.Shares, Amount: p.Amount})
+		}
+	}
+	return msg
+}
+
+// grpcError mirrors the REST status mapping: budget rejections and closed or
+// reconciled periods are a failed precondition, anything else from validation is an invalid argument.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrBudgetExceeded), errors.Is(err, ErrPeriodClosed), errors.Is(err, ErrPeriodReconciled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrTransactionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package ledger
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/ledgerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newGRPCClient(t *testing.T, ledger *Ledger) ledgerpb.LedgerServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	NewGRPCServer(ledger).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return ledgerpb.NewLedgerServiceClient(conn)
+}
+
+func TestGRPCServer(t *testing.T) {
+	ledger := NewLedger()
+	client := newGRPCClient(t, ledger)
+	ctx := context.Background()
+	date := timestamppb.New(time.Now().Add(-time.Hour))
+
+	t.Run("set budget", func(t *testing.T) {
+		budget, err := client.SetBudget(ctx, &ledgerpb.SetBudgetRequest{Category: "food", Limit: 1000})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if budget.GetLimit() != 1000 {
+			t.Errorf("Expected limit 1000, got %f", budget.GetLimit())
+		}
+
+		_, err = client.SetBudget(ctx, &ledgerpb.SetBudgetRequest{Category: "food", Limit: -1})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument, got %v", err)
+		}
+	})
+
+	t.Run("create transaction", func(t *testing.T) {
+		tx, err := client.CreateTransaction(ctx, &ledgerpb.CreateTransactionRequest{
+			Amount: 600, Category: "food", Date: date, Type: "expense",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if tx.GetId() == "" {
+			t.Error("Expected generated ID")
+		}
+
+		_, err = client.CreateTransaction(ctx, &ledgerpb.CreateTransactionRequest{
+			Amount: 600, Category: "food", Date: date, Type: "expense",
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition, got %v", err)
+		}
+
+		_, err = client.CreateTransaction(ctx, &ledgerpb.CreateTransactionRequest{
+			Amount: 10, Category: "food", Type: "expense",
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for missing date, got %v", err)
+		}
+	})
+
+	t.Run("list transactions", func(t *testing.T) {
+		if err := ledger.AddTransaction(&Transaction{
+			ID: "income", Amount: 50, Category: "salary", Date: time.Now(), Type: "income",
+		}); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+
+		stream, err := client.ListTransactions(ctx, &ledgerpb.ListTransactionsRequest{Type: "expense"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var count int
+		for {
+			tx, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Stream failed: %v", err)
+			}
+			if tx.GetType() != "expense" {
+				t.Errorf("Expected expense, got %s", tx.GetType())
+			}
+			count++
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 expense, got %d", count)
+		}
+	})
+
+	t.Run("list budgets", func(t *testing.T) {
+		resp, err := client.ListBudgets(ctx, &ledgerpb.ListBudgetsRequest{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(resp.GetBudgets()) != 1 || resp.GetBudgets()[0].GetSpent() != 600 {
+			t.Errorf("Expected one budget with 600 spent, got %v", resp.GetBudgets())
+		}
+	})
+
+	t.Run("watch budgets", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		stream, err := client.WatchBudgets(watchCtx, &ledgerpb.WatchBudgetsRequest{Categories: []string{"food"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		initial, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Failed to receive snapshot: %v", err)
+		}
+		if initial.GetSpent() != 600 {
+			t.Errorf("Expected snapshot with 600 spent, got %f", initial.GetSpent())
+		}
+
+		if err := ledger.SetBudget(&Budget{Category: "transport", Limit: 100}); err != nil {
+			t.Fatalf("Failed to set budget: %v", err)
+		}
+		if _, err := client.CreateTransaction(ctx, &ledgerpb.CreateTransactionRequest{
+			Amount: 100, Category: "food", Date: date, Type: "expense",
+		}); err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Failed to receive update: %v", err)
+		}
+		if update.GetCategory() != "food" || update.GetSpent() != 700 {
+			t.Errorf("Expected food update with 700 spent, got %v", update)
+		}
+	})
+}
@@ -2,7 +2,9 @@
 package ledger
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -10,20 +12,50 @@ import (
 )
 
 type CreateTransactionRequest struct {
-	Amount      float64 `json:"amount"`
-	Category    string  `json:"category"`
-	Description string  `json:"description,omitempty"`
-	Date        string  `json:"date"`
-	Type        string  `json:"type"`
+	Amount float64 `json:"amount"`
+	// Category may be omitted for split transactions, which are then filed
+	// under their largest line.
+	Category    string            `json:"category,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Date        string            `json:"date" format:"date"`
+	Type        string            `json:"type" enum:"income,expense,transfer"`
+	Splits      []SplitRequest    `json:"splits,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Sharing     *SharingRequest   `json:"sharing,omitempty"`
+}
+
+type SplitRequest struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Note     string  `json:"note,omitempty"`
 }
 
 type TransactionResponse struct {
-	ID          string    `json:"id"`
-	Amount      float64   `json:"amount"`
-	Category    string    `json:"category"`
-	Description string    `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Type        string    `json:"type"`
+	ID          string               `json:"id"`
+	Amount      float64              `json:"amount"`
+	Category    string               `json:"category"`
+	Description string               `json:"description,omitempty"`
+	Date        time.Time            `json:"date"`
+	Type        string               `json:"type" enum:"income,expense,transfer"`
+	Status      string               `json:"status" enum:"pending,uncleared,cleared,reconciled"`
+	Splits      []SplitResponse      `json:"splits,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Metadata    map[string]string    `json:"metadata,omitempty"`
+	Sharing     *SharingResponse     `json:"sharing,omitempty"`
+	Attachments []AttachmentResponse `json:"attachments,omitempty"`
+	Anomalies   []AnomalyResponse    `json:"anomalies,omitempty"`
+}
+
+type SplitResponse struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Note     string  `json:"note,omitempty"`
+}
+
+type AnomalyResponse struct {
+	Kind   string `json:"kind" enum:"outlier,duplicate,new_category"`
+	Detail string `json:"detail"`
 }
 
 type CreateBudgetRequest struct {
@@ -31,22 +63,98 @@ type CreateBudgetRequest struct {
 	Limit    float64 `json:"limit"`
 }
 
+// BudgetResponse splits the limit into what posted expenses have spent, what
+// pending ones have committed and what is still available.
 type BudgetResponse struct {
-	Category string  `json:"category"`
-	Limit    float64 `json:"limit"`
-	Spent    float64 `json:"spent"`
+	Category  string  `json:"category"`
+	Limit     float64 `json:"limit"`
+	Spent     float64 `json:"spent"`
+	Committed float64 `json:"committed"`
+	Available float64 `json:"available"`
+}
+
+func newBudgetResponse(b Budget) BudgetResponse {
+	return BudgetResponse{
+		Category:  b.Category,
+		Limit:     b.Limit,
+		Spent:     b.Spent,
+		Committed: b.Committed,
+		Available: b.Limit - b.Spent - b.Committed,
+	}
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
+const (
+	ErrCodeBadRequest       = "bad_request"
+	ErrCodeUnauthorized     = "unauthorized"
+	ErrCodeValidation       = "validation_failed"
+	ErrCodeBudgetExceeded   = "budget_exceeded"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeConflict         = "conflict"
+	ErrCodeIdempotencyKey   = "idempotency_key_reused"
+	ErrCodePeriodLocked     = "period_locked"
+	ErrCodeBatchRejected    = "batch_rejected"
+	ErrCodeRateLimited      = "rate_limited"
+	ErrCodeTooLarge         = "payload_too_large"
+	ErrCodeUnsupportedMedia = "unsupported_media_type"
+	ErrCodeInternal         = "internal"
+)
+
 type Handler struct {
-	ledger *Ledger
+	ledger      *Ledger
+	idempotency *idempotencyStore
+	admins      map[string]string
 }
 
 func NewHandler(ledger *Ledger) *Handler {
-	return &Handler{ledger: ledger}
+	return &Handler{
+		ledger:      ledger,
+		idempotency: newIdempotencyStore(),
+	}
+}
+
+// transaction builds a new transaction with a fresh ID from the request.
+func (req CreateTransactionRequest) transaction() (*Transaction, error) {
+	date, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date format, use YYYY-MM-DD")
+	}
+
+	tx := &Transaction{
+		ID:          uuid.New().String(),
+		Amount:      req.Amount,
+		Category:    req.Category,
+		Description: req.Description,
+		Date:        date,
+		Type:        req.Type,
+		Tags:        req.Tags,
+		Metadata:    req.Metadata,
+		Sharing:     req.Sharing.sharing(),
+	}
+	for _, line := range req.Splits {
+		tx.Splits = append(tx.Splits, Split{Category: line.Category, Amount: line.Amount, Note: line.Note})
+	}
+	return tx, nil
+}
+
+// transactionErrorStatus maps an error adding a transaction to its status and
+// error code.
+func transactionErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrBudgetExceeded):
+		return http.StatusConflict, ErrCodeBudgetExceeded
+	case errors.Is(err, ErrPeriodClosed), errors.Is(err, ErrPeriodReconciled):
+		return http.StatusConflict, ErrCodePeriodLocked
+	case errors.Is(err, ErrBatchRejected):
+		return http.StatusConflict, ErrCodeBatchRejected
+	default:
+		return http.StatusBadRequest, ErrCodeValidation
+	}
 }
 
 func (h *Handler) CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
@@ -61,31 +169,75 @@ func (h *Handler) CreateTransactionHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	// Reserving the key makes a concurrent retry wait for this request's
+	// response instead of creating a second transaction. The key is bound to
+	// the decoded request, so formatting does not tell retries apart.
+	key := r.Header.Get(IdempotencyKeyHeader)
+	canonical, _ := json.Marshal(req)
+	cached, exists, err := h.idempotency.reserve(r.Context(), key, sha256.Sum256(canonical))
+	if errors.Is(err, ErrIdempotencyKeyReused) {
+		writeErrorCode(w, http.StatusUnprocessableEntity, ErrCodeIdempotencyKey, err.Error())
+		return
+	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid date format, use YYYY-MM-DD")
 		return
 	}
+	if exists {
+		writeJSON(w, http.StatusCreated, cached)
+		return
+	}
+	defer h.idempotency.release(key)
 
-	tx := &Transaction{
-		ID:          uuid.New().String(),
-		Amount:      req.Amount,
-		Category:    req.Category,
-		Description: req.Description,
-		Date:        date,
-		Type:        req.Type,
+	tx, err := req.transaction()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := h.ledger.AddTransaction(tx); err != nil {
-		switch err {
-		case ErrBudgetExceeded:
-			writeError(w, http.StatusConflict, "budget exceeded")
-		default:
-			writeError(w, http.StatusBadRequest, err.Error())
+	if err := h.ledger.AddTransactionContext(r.Context(), tx); err != nil {
+		var exceeded *BudgetExceededError
+		if errors.As(err, &exceeded) {
+			Logger(r.Context()).Warn("budget exceeded",
+				"category", exceeded.Category,
+				"amount", exceeded.Amount,
+				"spent", exceeded.Used,
+				"limit", exceeded.Limit,
+			)
 		}
+		status, code := transactionErrorStatus(err)
+		writeErrorCode(w, status, code, err.Error())
 		return
 	}
 
+	response := newTransactionResponse(tx)
+
+	h.idempotency.put(key, response)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handler) ListTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := ParseTransactionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transactions := h.ledger.FilterTransactions(filter)
+	response := make([]TransactionResponse, len(transactions))
+
+	for i, tx := range transactions {
+		response[i] = newTransactionResponse(tx)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func newTransactionResponse(tx *Transaction) TransactionResponse {
 	response := TransactionResponse{
 		ID:          tx.ID,
 		Amount:      tx.Amount,
@@ -93,29 +245,41 @@ func (h *Handler) CreateTransactionHandler(w http.ResponseWriter, r *http.Reques
 		Description: tx.Description,
 		Date:        tx.Date,
 		Type:        tx.Type,
+		Status:      tx.Status,
+		Tags:        tx.Tags,
+		Metadata:    tx.Metadata,
+		Sharing:     newSharingResponse(tx),
 	}
-
-	writeJSON(w, http.StatusCreated, response)
+	for _, line := range tx.Splits {
+		response.Splits = append(response.Splits, SplitResponse{Category: line.Category, Amount: line.Amount, Note: line.Note})
+	}
+	for _, attachment := range tx.Attachments {
+		response.Attachments = append(response.Attachments, newAttachmentResponse(tx.ID, attachment))
+	}
+	for _, a := range tx.Anomalies {
+		response.Anomalies = append(response.Anomalies, AnomalyResponse{Kind: a.Kind, Detail: a.Detail})
+	}
+	return response
 }
 
-func (h *Handler) ListTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListAnomaliesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	transactions := h.ledger.ListTransactions()
-	response := make([]TransactionResponse, len(transactions))
+	kind := r.URL.Query().Get("kind")
+	switch kind {
+	case "", AnomalyOutlier, AnomalyDuplicate, AnomalyNewCategory:
+	default:
+		writeError(w, http.StatusBadRequest, "kind must be 'outlier', 'duplicate' or 'new_category'")
+		return
+	}
 
+	transactions := h.ledger.ListAnomalies(kind)
+	response := make([]TransactionResponse, len(transactions))
 	for i, tx := range transactions {
-		response[i] = TransactionResponse{
-			ID:          tx.ID,
-			Amount:      tx.Amount,
-			Category:    tx.Category,
-			Description: tx.Description,
-			Date:        tx.Date,
-			Type:        tx.Type,
-		}
+		response[i] = newTransactionResponse(tx)
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -139,16 +303,16 @@ func (h *Handler) CreateBudgetHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.ledger.SetBudget(budget); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
 		return
 	}
 
-	spent := h.ledger.getCategorySpending(req.Category)
-	response := BudgetResponse{
-		Category: budget.Category,
-		Limit:    budget.Limit,
-		Spent:    spent,
-	}
+	response := newBudgetResponse(Budget{
+		Category:  budget.Category,
+		Limit:     budget.Limit,
+		Spent:     h.ledger.GetCategorySpending(budget.Category),
+		Committed: h.ledger.GetCategoryCommitted(budget.Category),
+	})
 
 	writeJSON(w, http.StatusCreated, response)
 }
@@ -163,17 +327,26 @@ func (h *Handler) ListBudgetsHandler(w http.ResponseWriter, r *http.Request) {
 	response := make([]BudgetResponse, len(budgets))
 
 	for i, budget := range budgets {
-		spent := h.ledger.getCategorySpending(budget.Category)
-		response[i] = BudgetResponse{
-			Category: budget.Category,
-			Limit:    budget.Limit,
-			Spent:    spent,
-		}
+		response[i] = newBudgetResponse(Budget{
+			Category:  budget.Category,
+			Limit:     budget.Limit,
+			Spent:     h.ledger.GetCategorySpending(budget.Category),
+			Committed: h.ledger.GetCategoryCommitted(budget.Category),
+		})
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
 
+func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -181,5 +354,32 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 }
 
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, ErrorResponse{Error: message})
+	writeErrorCode(w, status, errorCode(status), message)
+}
+
+func writeErrorCode(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message, Code: code})
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodeTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrCodeUnsupportedMedia
+	default:
+		return ErrCodeInternal
+	}
 }
@@ -0,0 +1,158 @@
+package ledger
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStarting     = "starting"
+	HealthOK           = "ok"
+	HealthFailed       = "failed"
+	HealthShuttingDown = "shutting_down"
+)
+
+// HealthChecker reports whether a dependency is usable; a nil error means
+// healthy.
+type HealthChecker func(ctx context.Context) error
+
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status" enum:"ok,failed"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string              `json:"status" enum:"starting,ok,failed,shutting_down"`
+	Checks []HealthCheckResult `json:"checks,omitempty"`
+}
+
+// Health serves liveness and readiness. It starts not ready; the server marks
+// it ready once it accepts traffic and shutting down before draining, so load
+// balancers stop routing to it first.
+type Health struct {
+	// Timeout bounds each readiness check.
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	state  string
+	names  []string
+	checks map[string]HealthChecker
+}
+
+func NewHealth() *Health {
+	return &Health{
+		Timeout: 2 * time.Second,
+		state:   HealthStarting,
+		checks:  make(map[string]HealthChecker),
+	}
+}
+
+func (h *Health) Register(name string, check HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.checks[name]; !exists {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+func (h *Health) SetReady() {
+	h.setState(HealthOK)
+}
+
+func (h *Health) SetShuttingDown() {
+	h.setState(HealthShuttingDown)
+}
+
+func (h *Health) setState(state string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state = state
+}
+
+// Check runs all registered checks concurrently.
+func (h *Health) Check(ctx context.Context) HealthResponse {
+	h.mu.RLock()
+	state := h.state
+	names := append([]string(nil), h.names...)
+	checks := make([]HealthChecker, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]HealthCheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, names[i], checks[i])
+		}()
+	}
+	wg.Wait()
+
+	response := HealthResponse{Status: state, Checks: results}
+	if state == HealthOK {
+		for _, result := range results {
+			if result.Status != HealthOK {
+				response.Status = HealthFailed
+			}
+		}
+	}
+	return response
+}
+
+func (h *Health) run(ctx context.Context, name string, check HealthChecker) HealthCheckResult {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check(ctx)
+	result := HealthCheckResult{
+		Name:      name,
+		Status:    HealthOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LiveHandler reports that the process is up and serving; it never runs
+// dependency checks, so a broken dependency does not get the process killed.
+func (h *Health) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HealthResponse{Status: HealthOK})
+}
+
+// ReadyHandler answers 200 only when the server is ready and every check
+// passes, and 503 otherwise.
+func (h *Health) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	response := h.Check(r.Context())
+	status := http.StatusOK
+	if response.Status != HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
@@ -0,0 +1,98 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth_ReadyHandler(t *testing.T) {
+	health := NewHealth()
+	health.Timeout = 50 * time.Millisecond
+
+	var storageErr error
+	health.Register("storage", func(context.Context) error { return storageErr })
+	health.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ready := func() (int, HealthResponse) {
+		rr := httptest.NewRecorder()
+		health.ReadyHandler(rr, httptest.NewRequest("GET", "/health/ready", nil))
+
+		var response HealthResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return rr.Code, response
+	}
+
+	t.Run("starting", func(t *testing.T) {
+		status, response := ready()
+		if status != http.StatusServiceUnavailable || response.Status != HealthStarting {
+			t.Errorf("Expected 503 %s, got %d %s", HealthStarting, status, response.Status)
+		}
+	})
+
+	t.Run("failing check", func(t *testing.T) {
+		health.SetReady()
+		status, response := ready()
+		if status != http.StatusServiceUnavailable || response.Status != HealthFailed {
+			t.Fatalf("Expected 503 %s, got %d %s", HealthFailed, status, response.Status)
+		}
+		if len(response.Checks) != 2 {
+			t.Fatalf("Expected 2 checks, got %d", len(response.Checks))
+		}
+
+		storage, slow := response.Checks[0], response.Checks[1]
+		if storage.Name != "storage" || storage.Status != HealthOK {
+			t.Errorf("Expected storage check to pass, got %+v", storage)
+		}
+		if slow.Name != "slow" || slow.Status != HealthFailed || slow.Error == "" {
+			t.Errorf("Expected slow check to time out, got %+v", slow)
+		}
+		if slow.LatencyMs < 50 {
+			t.Errorf("Expected slow check latency of at least 50ms, got %v", slow.LatencyMs)
+		}
+	})
+
+	t.Run("all checks pass", func(t *testing.T) {
+		health.Register("slow", func(context.Context) error { return nil })
+		status, response := ready()
+		if status != http.StatusOK || response.Status != HealthOK {
+			t.Errorf("Expected 200 %s, got %d %s", HealthOK, status, response.Status)
+		}
+		if len(response.Checks) != 2 {
+			t.Errorf("Expected re-registering a check to replace it, got %d checks", len(response.Checks))
+		}
+	})
+
+	t.Run("storage down", func(t *testing.T) {
+		storageErr = errors.New("disk gone")
+		defer func() { storageErr = nil }()
+
+		_, response := ready()
+		if response.Checks[0].Error != "disk gone" {
+			t.Errorf("Expected storage error to be reported, got %+v", response.Checks[0])
+		}
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		health.SetShuttingDown()
+		status, response := ready()
+		if status != http.StatusServiceUnavailable || response.Status != HealthShuttingDown {
+			t.Errorf("Expected 503 %s, got %d %s", HealthShuttingDown, status, response.Status)
+		}
+
+		rr := httptest.NewRecorder()
+		health.LiveHandler(rr, httptest.NewRequest("GET", "/health/live", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected liveness to stay OK while shutting down, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package ledger
+
+// Hooks observe ledger events for instrumentation. Nil fields are skipped.
+// Hooks run while the ledger is locked and must not call back into it.
+type Hooks struct {
+	TransactionAdded func(tx *Transaction)
+	BudgetExceeded   func(category string, amount float64)
+	// BudgetChanged reports the spending of a budgeted category after a
+	// budget is set or its spending changes.
+	BudgetChanged func(category string, spent, limit float64)
+}
+
+func (l *Ledger) SetHooks(hooks Hooks) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = hooks
+	for category, budget := range l.Budgets {
+		l.budgetChanged(category, budget)
+	}
+}
+
+func (l *Ledger) transactionAdded(tx *Transaction) {
+	if l.hooks.TransactionAdded != nil {
+		l.hooks.TransactionAdded(tx)
+	}
+}
+
+func (l *Ledger) budgetExceeded(category string, amount float64) {
+	if l.hooks.BudgetExceeded != nil {
+		l.hooks.BudgetExceeded(category, amount)
+	}
+}
+
+func (l *Ledger) budgetChanged(category string, budget *Budget) {
+	if l.hooks.BudgetChanged != nil {
+		l.hooks.BudgetChanged(category, l.spending.total(category), budget.Limit)
+	}
+}
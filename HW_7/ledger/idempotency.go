@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+const (
+	// idempotencyTTL is how long a response is replayed for its key.
+	idempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeys bounds memory: past it, the oldest responses are
+	// forgotten before they expire.
+	maxIdempotencyKeys = 10000
+)
+
+// idempotencyStore remembers the response for each Idempotency-Key so a
+// retried create returns the original transaction instead of a duplicate.
+// Each key is tied to a hash of its request, so a different request under the
+// same key is rejected rather than answered with the first one's response.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	// order holds entries oldest first; it may still list entries that have
+	// been released or replaced.
+	order []*idempotencyEntry
+	now   func() time.Time
+}
+
+type idempotencyEntry struct {
+	key      string
+	request  [sha256.Size]byte
+	response TransactionResponse
+	stored   bool
+	expires  time.Time
+	// done is closed once the response is stored or the key released.
+	done chan struct{}
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// reserve returns the response stored for key. Otherwise it reserves the key
+// for the caller, who must then put a response or release it; requests with a
+// reserved key wait for that outcome. A key held for a request with another
+// hash fails with ErrIdempotencyKeyReused.
+func (s *idempotencyStore) reserve(ctx context.Context, key string, request [sha256.Size]byte) (TransactionResponse, bool, error) {
+	if key == "" {
+		return TransactionResponse{}, false, nil
+	}
+
+	for {
+		s.mu.Lock()
+		now := s.now()
+		s.expire(now)
+		entry, exists := s.entries[key]
+		if !exists {
+			entry = &idempotencyEntry{key: key, request: request, expires: now.Add(idempotencyTTL), done: make(chan struct{})}
+			s.entries[key] = entry
+			s.order = append(s.order, entry)
+			s.mu.Unlock()
+			return TransactionResponse{}, false, nil
+		}
+		s.mu.Unlock()
+		if entry.request != request {
+			return TransactionResponse{}, false, ErrIdempotencyKeyReused
+		}
+
+		select {
+		case <-entry.done:
+			if entry.stored {
+				return entry.response, true, nil
+			}
+			// The request holding the key failed; try to take it over.
+		case <-ctx.Done():
+			return TransactionResponse{}, false, ctx.Err()
+		}
+	}
+}
+
+func (s *idempotencyStore) put(key string, response TransactionResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, exists := s.entries[key]; exists && !entry.stored {
+		entry.response = response
+		entry.stored = true
+		close(entry.done)
+	}
+}
+
+// release gives up a reservation that produced no response, so the key can
+// be retried. Keys with a stored response are kept.
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, exists := s.entries[key]; exists && !entry.stored {
+		delete(s.entries, key)
+		close(entry.done)
+	}
+}
+
+// expire drops expired responses, and the oldest ones while the store is
+// full. Reservations in flight are kept.
+func (s *idempotencyStore) expire(now time.Time) {
+	for len(s.order) > 0 {
+		entry := s.order[0]
+		if s.entries[entry.key] == entry {
+			if !entry.stored || (now.Before(entry.expires) && len(s.entries) < maxIdempotencyKeys) {
+				return
+			}
+			delete(s.entries, entry.key)
+		}
+		s.order = s.order[1:]
+	}
+}
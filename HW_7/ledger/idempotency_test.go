@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateTransactionHandler_ConcurrentIdempotencyKey(t *testing.T) {
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 20)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/api/transactions", strings.NewReader(`{"amount":10,"category":"food","date":"2024-01-15","type":"expense"}`))
+			req.Header.Set(IdempotencyKeyHeader, "retry-1")
+			rr := httptest.NewRecorder()
+			handler.CreateTransactionHandler(rr, req)
+			codes[i] = rr.Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusCreated {
+			t.Errorf("Request %d: expected status %d, got %d", i, http.StatusCreated, code)
+		}
+	}
+	if n := len(ledger.ListTransactions()); n != 1 {
+		t.Errorf("Expected 1 transaction, got %d", n)
+	}
+}
+
+func TestCreateTransactionHandler_IdempotencyKeyReused(t *testing.T) {
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"first request", `{"amount":10,"category":"food","date":"2024-01-15","type":"expense"}`, http.StatusCreated},
+		{"retry", `{ "type":"expense", "date":"2024-01-15", "category":"food", "amount":10 }`, http.StatusCreated},
+		{"different amount", `{"amount":12,"category":"food","date":"2024-01-15","type":"expense"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/transactions", strings.NewReader(tt.body))
+			req.Header.Set(IdempotencyKeyHeader, "retry-1")
+			rr := httptest.NewRecorder()
+			handler.CreateTransactionHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+	if n := len(ledger.ListTransactions()); n != 1 {
+		t.Errorf("Expected 1 transaction, got %d", n)
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Now()
+	store := newIdempotencyStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	request := sha256.Sum256([]byte("request"))
+
+	t.Run("released keys can be retried", func(t *testing.T) {
+		store.reserve(ctx, "a", request)
+		store.release("a")
+		if _, exists, _ := store.reserve(ctx, "a", request); exists {
+			t.Error("Expected a released key to have no response")
+		}
+		store.put("a", TransactionResponse{ID: "tx-a"})
+		if response, exists, _ := store.reserve(ctx, "a", request); !exists || response.ID != "tx-a" {
+			t.Errorf("Expected the stored response, got %+v", response)
+		}
+	})
+
+	t.Run("waits for a reserved key", func(t *testing.T) {
+		store.reserve(ctx, "b", request)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			store.put("b", TransactionResponse{ID: "tx-b"})
+		}()
+		if response, exists, _ := store.reserve(ctx, "b", request); !exists || response.ID != "tx-b" {
+			t.Errorf("Expected to wait for the response, got %+v", response)
+		}
+
+		store.reserve(ctx, "c", request)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, _, err := store.reserve(cancelled, "c", request); err == nil {
+			t.Error("Expected waiting to stop with the context")
+		}
+		store.release("c")
+	})
+
+	t.Run("another request with the key", func(t *testing.T) {
+		if _, _, err := store.reserve(ctx, "a", sha256.Sum256([]byte("other"))); !errors.Is(err, ErrIdempotencyKeyReused) {
+			t.Errorf("Expected %v, got %v", ErrIdempotencyKeyReused, err)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		now = now.Add(idempotencyTTL)
+		if _, exists, _ := store.reserve(ctx, "a", request); exists {
+			t.Error("Expected the response to expire")
+		}
+		store.release("a")
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		for i := range maxIdempotencyKeys + 10 {
+			key := fmt.Sprint("key-", i)
+			store.reserve(ctx, key, request)
+			store.put(key, TransactionResponse{ID: key})
+		}
+		if n := len(store.entries); n > maxIdempotencyKeys {
+			t.Errorf("Expected at most %d keys, got %d", maxIdempotencyKeys, n)
+		}
+		if _, exists, _ := store.reserve(ctx, "key-0", request); exists {
+			t.Error("Expected the oldest key to be evicted")
+		}
+	})
+}
@@ -1,92 +1,358 @@
 package ledger
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
-	ErrBudgetExceeded = errors.New("budget exceeded")
+	ErrBudgetExceeded      = errors.New("budget exceeded")
+	ErrTransactionNotFound = errors.New("transaction not found")
 )
 
+// BudgetExceededError reports the line that would take its category over
+// budget. Used counts spent and committed spending before the line.
+type BudgetExceededError struct {
+	Category string
+	Amount   float64
+	Used     float64
+	Limit    float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrBudgetExceeded, e.Category)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
 type Transaction struct {
-	ID          string    `json:"id"`
-	Amount      float64   `json:"amount"`
-	Category    string    `json:"category"`
-	Description string    `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Type        string    `json:"type"`
+	ID          string            `json:"id"`
+	Amount      float64           `json:"amount"`
+	Category    string            `json:"category"`
+	Description string            `json:"description,omitempty"`
+	Date        time.Time         `json:"date"`
+	Type        string            `json:"type"`
+	Status      string            `json:"status,omitempty"`
+	Splits      []Split           `json:"splits,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Sharing     *Sharing          `json:"sharing,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Anomalies   []Anomaly         `json:"anomalies,omitempty"`
 }
 
 type Budget struct {
 	Category string  `json:"category"`
 	Limit    float64 `json:"limit"`
 	Spent    float64 `json:"spent,omitempty"`
+	// Committed is what pending expenses will add to Spent.
+	Committed float64 `json:"committed,omitempty"`
 }
 
 type Ledger struct {
 	Transactions []*Transaction
 	Budgets      map[string]*Budget
+	Goals        map[string]*Goal
+
+	mu       sync.RWMutex
+	spending *spendingIndex
+	tags     tagIndex
+	watchers *budgetWatchers
+	hooks    Hooks
+	detector AnomalyDetector
+	blobs    BlobStore
+	policy   ValidationPolicy
+	events   *eventBus
+
+	reconciliations map[string]*Reconciliation
+	matcher         StatementMatcher
+	closedPeriods   map[string]*ClosedPeriod
+	periodAudit     []PeriodEvent
+
+	// uploads is held for reading by attachment uploads from storing their
+	// blob until a transaction refers to it, and for writing while orphaned
+	// blobs are deleted, so an upload of the same content is not lost.
+	uploads sync.RWMutex
 }
 
 func NewLedger() *Ledger {
 	return &Ledger{
 		Transactions: make([]*Transaction, 0),
 		Budgets:      make(map[string]*Budget),
+		Goals:        make(map[string]*Goal),
+		spending:     newSpendingIndex(),
+		tags:         make(tagIndex),
+		watchers:     newBudgetWatchers(),
+		detector:     NewStatisticalDetector(),
+		blobs:        newMemoryBlobStore(),
+		policy:       DefaultValidationPolicy(),
+		events:       newEventBus(),
+
+		reconciliations: make(map[string]*Reconciliation),
+		matcher:         DefaultStatementMatcher(),
+		closedPeriods:   make(map[string]*ClosedPeriod),
 	}
 }
 
 func (l *Ledger) AddTransaction(tx *Transaction) error {
-	if err := tx.Validate(); err != nil {
+	return l.AddTransactionContext(context.Background(), tx)
+}
+
+// AddTransactionContext is AddTransaction with tracing: the budget check and
+// the write are recorded as child spans of ctx.
+func (l *Ledger) AddTransactionContext(ctx context.Context, tx *Transaction) (err error) {
+	tx.fileSplit()
+	ctx, span := startSpan(ctx, "Ledger.AddTransaction",
+		attribute.String("ledger.category", tx.Category),
+		attribute.String("ledger.type", tx.Type),
+	)
+	defer func() { endSpan(span, err) }()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.admit(tx); err != nil {
+		return err
+	}
+	if err := l.checkBudget(ctx, tx); err != nil {
 		return err
 	}
 
-	if tx.Type == "expense" {
-		budget, exists := l.Budgets[tx.Category]
-		if exists {
-			currentSpent := l.GetCategorySpending(tx.Category)
-			if currentSpent+tx.Amount > budget.Limit {
-				return ErrBudgetExceeded
-			}
-		}
+	_, store := startSpan(ctx, "Ledger.store")
+	l.insert(tx)
+	store.End()
+	return nil
+}
+
+// admit runs the checks of a new transaction other than the budget check and
+// files its status.
+func (l *Ledger) admit(tx *Transaction) error {
+	if err := l.policy.Transaction.Validate(tx); err != nil {
+		return err
 	}
+	tx.fileStatus("", time.Now())
+	return l.checkEditable(nil, tx.Date)
+}
+
+// insert stores an admitted transaction and notifies its budgets.
+func (l *Ledger) insert(tx *Transaction) {
+	l.store(tx)
+	l.notifyBudgets(tx)
+}
 
+// store adds an admitted transaction without notifying budgets, so a batch
+// can notify each of its categories once.
+func (l *Ledger) store(tx *Transaction) {
+	tx.Anomalies = l.detector.Detect(tx)
 	l.Transactions = append(l.Transactions, tx)
+	l.spending.add(tx)
+	l.tags.add(tx)
+	l.detector.Observe(tx)
+
+	l.transactionAdded(tx)
+	l.transactionEvent(EventTransactionCreated, tx)
+}
+
+func (l *Ledger) checkBudget(ctx context.Context, tx *Transaction) (err error) {
+	_, span := startSpan(ctx, "Ledger.checkBudget")
+	defer func() { endSpan(span, err) }()
+
+	return l.overBudget(tx, nil, nil)
+}
+
+// overBudget returns a BudgetExceededError for the first category of tx whose
+// budget it would exceed, counting old as already removed and pending expenses
+// and batch, when given, as spent. The whole transaction is rejected if any
+// line is over. Lines no larger than old's in their category are let through,
+// so an edit can always bring an over-budget category down.
+func (l *Ledger) overBudget(tx, old *Transaction, batch *spendingIndex) error {
+	if tx.Type != "expense" {
+		return nil
+	}
+
+	previous := make(map[string]float64)
+	if old != nil && old.Type == "expense" {
+		for _, line := range old.byCategory() {
+			previous[line.Category] = line.Amount
+		}
+	}
+
+	for _, line := range tx.byCategory() {
+		budget, exists := l.Budgets[line.Category]
+		if !exists || line.Amount <= previous[line.Category] {
+			continue
+		}
+		used := l.spending.used(line.Category) + batch.used(line.Category) - previous[line.Category]
+		if used+line.Amount > budget.Limit {
+			l.budgetExceeded(line.Category, line.Amount)
+			return &BudgetExceededError{Category: line.Category, Amount: line.Amount, Used: used, Limit: budget.Limit}
+		}
+	}
 	return nil
 }
 
-func (l *Ledger) SetBudget(b *Budget) error {
-	if err := b.Validate(); err != nil {
+func (l *Ledger) UpdateTransaction(tx *Transaction) error {
+	tx.fileSplit()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := l.findTransaction(tx.ID)
+	if i < 0 {
+		return ErrTransactionNotFound
+	}
+	old := l.Transactions[i]
+	// Sending back the stored status, such as pending, is the same as
+	// sending none: it is filed again from the date.
+	if tx.Status == old.Status {
+		tx.Status = ""
+	}
+	if err := l.policy.Transaction.Validate(tx); err != nil {
+		return err
+	}
+	if err := l.checkEditable(old, tx.Date); err != nil {
+		return err
+	}
+
+	if err := l.overBudget(tx, old, nil); err != nil {
+		return err
+	}
+
+	if tx.Anomalies == nil {
+		tx.Anomalies = old.Anomalies
+	}
+	if tx.Attachments == nil {
+		tx.Attachments = old.Attachments
+	}
+	tx.fileStatus(old.Status, time.Now())
+	l.spending.remove(old)
+	l.Transactions[i] = tx
+	l.spending.add(tx)
+	l.tags.replace(old, tx, l.Transactions)
+	l.detector.Forget(old)
+	l.detector.Observe(tx)
+	l.transactionEvent(EventTransactionUpdated, tx)
+	l.notifyBudgets(old, tx)
+	return nil
+}
+
+// DeleteTransaction removes the transaction and then, outside the lock, the
+// blobs of attachments no other transaction shares.
+func (l *Ledger) DeleteTransaction(id string) error {
+	l.mu.Lock()
+
+	i := l.findTransaction(id)
+	if i < 0 {
+		l.mu.Unlock()
+		return ErrTransactionNotFound
+	}
+
+	old := l.Transactions[i]
+	if err := l.checkEditable(old); err != nil {
+		l.mu.Unlock()
 		return err
 	}
+	l.spending.remove(old)
+	l.Transactions = append(l.Transactions[:i], l.Transactions[i+1:]...)
+	l.tags.remove(old)
+	l.detector.Forget(old)
+	l.transactionEvent(EventTransactionDeleted, old)
+	l.notifyBudgets(old)
+	blobs := l.blobs
+	l.mu.Unlock()
 
+	l.deleteOrphanedBlobs(context.Background(), blobs, old.Attachments)
+	return nil
+}
+
+func (l *Ledger) findTransaction(id string) int {
+	for i, tx := range l.Transactions {
+		if tx.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *Ledger) SetBudget(b *Budget) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.policy.Budget.Validate(b); err != nil {
+		return err
+	}
 	l.Budgets[b.Category] = b
+	l.budgetChanged(b.Category, b)
+	l.watchers.notify(b.Category)
+	l.budgetEvent(b)
 	return nil
 }
 
 func (l *Ledger) ListTransactions() []*Transaction {
-	return l.Transactions
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	transactions := make([]*Transaction, len(l.Transactions))
+	copy(transactions, l.Transactions)
+	return transactions
 }
 
+func (l *Ledger) GetBudget(category string) (*Budget, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	budget, exists := l.Budgets[category]
+	return budget, exists
+}
+
+// ListBudgets returns budgets ordered by category.
 func (l *Ledger) ListBudgets() []*Budget {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	budgets := make([]*Budget, 0, len(l.Budgets))
 	for _, budget := range l.Budgets {
 		budgets = append(budgets, budget)
 	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].Category < budgets[j].Category })
 	return budgets
 }
 
 func (l *Ledger) GetCategorySpending(category string) float64 {
-	var total float64
-	for _, tx := range l.Transactions {
-		if tx.Category == category && tx.Type == "expense" {
-			total += tx.Amount
-		}
-	}
-	return total
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.spending.total(category)
+}
+
+func (l *Ledger) GetCategoryPeriodSpending(category string, period time.Time) float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.spending.period(category, period)
 }
 
-func (l *Ledger) Reset() {
-	l.Transactions = make([]*Transaction, 0)
-	l.Budgets = make(map[string]*Budget)
+func (l *Ledger) notifyBudgets(txs ...*Transaction) {
+	notified := make(map[string]bool)
+	for _, tx := range txs {
+		if tx.Type != "expense" {
+			continue
+		}
+		for _, line := range tx.byCategory() {
+			if budget, exists := l.Budgets[line.Category]; exists && !notified[line.Category] {
+				notified[line.Category] = true
+				l.budgetChanged(line.Category, budget)
+				l.watchers.notify(line.Category)
+				l.budgetEvent(budget)
+			}
+		}
+	}
 }
@@ -1,6 +1,8 @@
 package ledger
 
 import (
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -55,6 +57,41 @@ func TestTransaction_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "category cannot be empty",
 		},
+		{
+			name: "splits summing to amount",
+			transaction: Transaction{
+				Amount:   100.0,
+				Category: "food",
+				Date:     time.Now().Add(-24 * time.Hour),
+				Type:     "expense",
+				Splits:   []Split{{Category: "food", Amount: 70.004}, {Category: "household", Amount: 30}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "splits not summing to amount",
+			transaction: Transaction{
+				Amount:   100.0,
+				Category: "food",
+				Date:     time.Now().Add(-24 * time.Hour),
+				Type:     "expense",
+				Splits:   []Split{{Category: "food", Amount: 70}, {Category: "household", Amount: 20}},
+			},
+			wantErr: true,
+			errMsg:  "splits sum to 90.00 but amount is 100.00",
+		},
+		{
+			name: "split without category",
+			transaction: Transaction{
+				Amount:   100.0,
+				Category: "food",
+				Date:     time.Now().Add(-24 * time.Hour),
+				Type:     "expense",
+				Splits:   []Split{{Category: "food", Amount: 70}, {Amount: 30}},
+			},
+			wantErr: true,
+			errMsg:  "split category cannot be empty",
+		},
 		{
 			name: "zero date",
 			transaction: Transaction{
@@ -86,7 +123,7 @@ func TestTransaction_Validate(t *testing.T) {
 				Type:     "invalid",
 			},
 			wantErr: true,
-			errMsg:  "type must be 'income' or 'expense'",
+			errMsg:  "type must be 'income', 'expense' or 'transfer'",
 		},
 	}
 
@@ -212,7 +249,7 @@ func TestLedger_BudgetExceeded(t *testing.T) {
 		}
 
 		err := ledger.AddTransaction(tx)
-		if err != ErrBudgetExceeded {
+		if !errors.Is(err, ErrBudgetExceeded) {
 			t.Errorf("Expected ErrBudgetExceeded, got %v", err)
 		}
 
@@ -281,3 +318,161 @@ func TestLedger_ListFunctions(t *testing.T) {
 		}
 	})
 }
+
+func TestLedger_SpendingAggregates(t *testing.T) {
+	ledger := NewLedger()
+
+	t.Cleanup(func() {
+		ledger.Reset()
+	})
+
+	if err := ledger.SetBudget(&Budget{Category: "food", Limit: 1000.0}); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	txs := []*Transaction{
+		{ID: "1", Amount: 300.0, Category: "food", Date: lastMonth, Type: "expense"},
+		{ID: "2", Amount: 200.0, Category: "food", Date: time.Now(), Type: "expense"},
+		{ID: "3", Amount: 50.0, Category: "transport", Date: time.Now(), Type: "expense"},
+		{ID: "4", Amount: 5000.0, Category: "food", Date: time.Now(), Type: "income"},
+	}
+	mustAdd(t, ledger, txs...)
+
+	t.Run("insert", func(t *testing.T) {
+		if got := ledger.GetCategorySpending("food"); got != 500.0 {
+			t.Errorf("Expected food spending 500, got %f", got)
+		}
+		if got := ledger.GetCategoryPeriodSpending("food", lastMonth); got != 300.0 {
+			t.Errorf("Expected last month food spending 300, got %f", got)
+		}
+		if got := ledger.GetCategoryPeriodSpending("food", time.Now()); got != 200.0 {
+			t.Errorf("Expected current month food spending 200, got %f", got)
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		tx := &Transaction{ID: "2", Amount: 700.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := ledger.GetCategorySpending("food"); got != 1000.0 {
+			t.Errorf("Expected food spending 1000, got %f", got)
+		}
+
+		tx = &Transaction{ID: "2", Amount: 800.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+		}
+
+		tx = &Transaction{ID: "3", Amount: 50.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Expected ErrBudgetExceeded when moving into a full category, got %v", err)
+		}
+
+		ledger.SetBudget(&Budget{Category: "food", Limit: 900.0})
+		tx = &Transaction{ID: "2", Amount: 650.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); err != nil {
+			t.Errorf("Expected lowering an over-budget expense to succeed, got %v", err)
+		}
+		tx = &Transaction{ID: "2", Amount: 660.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Expected ErrBudgetExceeded when raising an over-budget expense, got %v", err)
+		}
+		ledger.SetBudget(&Budget{Category: "food", Limit: 1000.0})
+		if err := ledger.UpdateTransaction(&Transaction{ID: "2", Amount: 700.0, Category: "food", Date: time.Now(), Type: "expense"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		tx = &Transaction{ID: "missing", Amount: 1.0, Category: "food", Date: time.Now(), Type: "expense"}
+		if err := ledger.UpdateTransaction(tx); err != ErrTransactionNotFound {
+			t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := ledger.DeleteTransaction("1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := ledger.GetCategorySpending("food"); got != 700.0 {
+			t.Errorf("Expected food spending 700, got %f", got)
+		}
+		if got := ledger.GetCategoryPeriodSpending("food", lastMonth); got != 0 {
+			t.Errorf("Expected last month food spending 0, got %f", got)
+		}
+		if err := ledger.DeleteTransaction("1"); err != ErrTransactionNotFound {
+			t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("consistency", func(t *testing.T) {
+		if err := ledger.CheckConsistency(); err != nil {
+			t.Errorf("Expected consistent aggregates, got %v", err)
+		}
+
+		ledger.Transactions = append(ledger.Transactions, &Transaction{
+			ID: "5", Amount: 10.0, Category: "food", Date: time.Now(), Type: "expense",
+		})
+		if err := ledger.CheckConsistency(); err == nil {
+			t.Error("Expected consistency error after bypassing AddTransaction")
+		}
+	})
+}
+
+// mustAdd adds the transactions to l, failing the test on the first error.
+func mustAdd(t testing.TB, l *Ledger, txs ...*Transaction) {
+	t.Helper()
+
+	for _, tx := range txs {
+		if err := l.AddTransaction(tx); err != nil {
+			t.Fatalf("Failed to add transaction %s: %v", tx.ID, err)
+		}
+	}
+}
+
+func seedLedger(b *testing.B, n int) *Ledger {
+	b.Helper()
+
+	ledger := NewLedger()
+	categories := []string{"food", "transport", "entertainment", "health", "utilities"}
+	start := time.Now().AddDate(-1, 0, 0)
+	for i := 0; i < n; i++ {
+		mustAdd(b, ledger, &Transaction{
+			ID:       strconv.Itoa(i),
+			Amount:   float64(i%100 + 1),
+			Category: categories[i%len(categories)],
+			Date:     start.Add(time.Duration(i) * time.Second),
+			Type:     "expense",
+		})
+	}
+	return ledger
+}
+
+func BenchmarkLedger_GetCategorySpending(b *testing.B) {
+	for _, n := range []int{1_000, 100_000, 1_000_000} {
+		ledger := seedLedger(b, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ledger.GetCategorySpending("food")
+			}
+		})
+	}
+}
+
+func BenchmarkLedger_AddTransactionWithBudget(b *testing.B) {
+	for _, n := range []int{1_000, 100_000, 1_000_000} {
+		ledger := seedLedger(b, n)
+		ledger.SetBudget(&Budget{Category: "food", Limit: 1e12})
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ledger.AddTransaction(&Transaction{
+					ID:       "bench",
+					Amount:   1.0,
+					Category: "food",
+					Date:     time.Now(),
+					Type:     "expense",
+				})
+			}
+		})
+	}
+}
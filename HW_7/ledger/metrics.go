@@ -0,0 +1,104 @@
+package ledger
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics collects HTTP and ledger metrics on its own registry, so several
+// instances (e.g. in tests) never clash on registration.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	transactions      *prometheus.CounterVec
+	budgetRejections  *prometheus.CounterVec
+	budgetUtilization *prometheus.GaugeVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_http_requests_total",
+			Help: "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ledger_http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_transactions_created_total",
+			Help: "Transactions created by type and category; a split transaction counts once per category.",
+		}, []string{"type", "category"}),
+		budgetRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_budget_rejections_total",
+			Help: "Expenses rejected for exceeding the category budget.",
+		}, []string{"category"}),
+		budgetUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ledger_budget_utilization_ratio",
+			Help: "Spent amount divided by the budget limit per category.",
+		}, []string{"category"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.transactions,
+		m.budgetRejections,
+		m.budgetUtilization,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Instrument installs hooks on l that feed the business metrics.
+func (m *Metrics) Instrument(l *Ledger) {
+	l.SetHooks(Hooks{
+		TransactionAdded: func(tx *Transaction) {
+			for _, line := range tx.byCategory() {
+				m.transactions.WithLabelValues(tx.Type, line.Category).Inc()
+			}
+		},
+		BudgetExceeded: func(category string, amount float64) {
+			m.budgetRejections.WithLabelValues(category).Inc()
+		},
+		BudgetChanged: func(category string, spent, limit float64) {
+			m.budgetUtilization.WithLabelValues(category).Set(spent / limit)
+		},
+	})
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records request count and latency. The route label is the
+// ServeMux pattern that served the request, so it must wrap the mux without
+// replacing the request in between; requests that never reach a pattern
+// (404s, rate-limited calls) are labelled "unmatched".
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(rw.status)
+		m.requests.WithLabelValues(r.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 200})
+
+	metrics := NewMetrics()
+	metrics.Instrument(ledger)
+	handler := NewHandler(ledger)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	server := httptest.NewServer(metrics.Middleware(mux))
+	t.Cleanup(server.Close)
+
+	for _, body := range []string{
+		`{"amount":50,"category":"food","date":"2024-01-15","type":"expense"}`,
+		`{"amount":500,"category":"food","date":"2024-01-16","type":"expense"}`,
+		`{"amount":1000,"category":"salary","date":"2024-01-17","type":"income"}`,
+	} {
+		resp, err := http.Post(server.URL+"/api/transactions", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if resp, err := http.Get(server.URL + "/unknown"); err == nil {
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	exposition := string(raw)
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text exposition format, got %s", ct)
+	}
+
+	tests := []string{
+		`ledger_http_requests_total{method="POST",route="POST /api/transactions",status="201"} 2`,
+		`ledger_http_requests_total{method="POST",route="POST /api/transactions",status="409"} 1`,
+		`ledger_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`ledger_http_request_duration_seconds_count{method="POST",route="POST /api/transactions",status="201"} 2`,
+		`ledger_transactions_created_total{category="food",type="expense"} 1`,
+		`ledger_transactions_created_total{category="salary",type="income"} 1`,
+		`ledger_budget_rejections_total{category="food"} 1`,
+		`ledger_budget_utilization_ratio{category="food"} 0.25`,
+	}
+
+	for _, want := range tests {
+		t.Run(want, func(t *testing.T) {
+			if !strings.Contains(exposition, want+"\n") {
+				t.Errorf("Expected metrics to contain %q", want)
+			}
+		})
+	}
+}
+
+func TestMetrics_InstrumentSeedsExistingBudgets(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "rent", Limit: 1000})
+	ledger.AddTransaction(&Transaction{ID: "1", Amount: 500, Category: "rent", Type: "expense", Date: time.Now()})
+
+	metrics := NewMetrics()
+	metrics.Instrument(ledger)
+
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	if want := `ledger_budget_utilization_ratio{category="rent"} 0.5`; !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("Expected metrics to contain %q", want)
+	}
+}
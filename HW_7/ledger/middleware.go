@@ -1,36 +1,146 @@
 package ledger
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const RequestIDHeader = "X-Request-ID"
+
+// maxErrorBodyCapture bounds how much of an error response is buffered to
+// pull the code out of ErrorResponse.
+const maxErrorBodyCapture = 4096
+
+// NewLogger builds a slog logger writing "json" or "text" records to w.
+func NewLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+type requestIDKey struct{}
+
+// requestSpanKey holds a *trace.SpanContext that TracingMiddleware fills in,
+// so LoggingMiddleware can tag the request line even though it runs outside
+// the server span.
+type requestSpanKey struct{}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger annotated with the request ID, trace ID
+// and span ID from ctx.
+func Logger(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+		if sc.HasSpanID() {
+			logger = logger.With("span_id", sc.SpanID().String())
+		}
+	}
+	return logger
+}
+
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		var span trace.SpanContext
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		r = r.WithContext(context.WithValue(ctx, requestSpanKey{}, &span))
+
 		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
 		next.ServeHTTP(rw, r)
 
-		duration := time.Since(start)
-		fmt.Printf("[%s] %s %s - %d - %v\n",
-			time.Now().Format("2006-01-02 15:04:05"),
-			r.Method,
-			r.URL.Path,
-			rw.status,
-			duration,
-		)
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rw.status,
+			"bytes", rw.size,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		}
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			attrs = append(attrs, "api_key", maskKey(key))
+		}
+
+		level := slog.LevelInfo
+		if rw.status >= 400 {
+			var errResp ErrorResponse
+			if json.Unmarshal(rw.errorBody.Bytes(), &errResp) == nil {
+				attrs = append(attrs, "error_code", errResp.Code, "error", errResp.Error)
+			}
+			level = slog.LevelWarn
+			if rw.status >= 500 {
+				level = slog.LevelError
+			}
+		}
+
+		ctx = r.Context()
+		if span.IsValid() {
+			ctx = trace.ContextWithSpanContext(ctx, span)
+		}
+		Logger(ctx).Log(ctx, level, "request", attrs...)
 	})
 }
 
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
+
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status    int
+	size      int
+	errorBody bytes.Buffer
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status >= 400 && rw.errorBody.Len() < maxErrorBodyCapture {
+		rw.errorBody.Write(b[:min(len(b), maxErrorBodyCapture-rw.errorBody.Len())])
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
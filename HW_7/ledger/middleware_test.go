@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(NewLogger(&buf, "json", slog.LevelInfo))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+	return &buf
+}
+
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to parse log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	logs := captureLogs(t)
+
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	server := LoggingMiddleware(mux)
+
+	t.Run("request ID is propagated", func(t *testing.T) {
+		logs.Reset()
+		body := `{"amount":50,"category":"food","date":"2024-01-15","type":"expense"}`
+		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body))
+		req.Header.Set(RequestIDHeader, "req-1")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get(RequestIDHeader); got != "req-1" {
+			t.Errorf("Expected response request ID req-1, got %q", got)
+		}
+
+		entries := logEntries(t, logs)
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 log entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		if entry["request_id"] != "req-1" || entry["status"] != float64(http.StatusCreated) {
+			t.Errorf("Unexpected log entry %v", entry)
+		}
+		if entry["bytes"] != float64(rr.Body.Len()) {
+			t.Errorf("Expected bytes %d, got %v", rr.Body.Len(), entry["bytes"])
+		}
+	})
+
+	t.Run("budget rejection is logged with error code", func(t *testing.T) {
+		logs.Reset()
+		body := `{"amount":80,"category":"food","date":"2024-01-15","type":"expense"}`
+		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		id := rr.Header().Get(RequestIDHeader)
+		if id == "" {
+			t.Fatal("Expected generated request ID")
+		}
+
+		entries := logEntries(t, logs)
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 log entries, got %d", len(entries))
+		}
+
+		rejection, request := entries[0], entries[1]
+		if rejection["msg"] != "budget exceeded" || rejection["category"] != "food" ||
+			rejection["amount"] != float64(80) || rejection["spent"] != float64(50) || rejection["limit"] != float64(100) {
+			t.Errorf("Unexpected rejection entry %v", rejection)
+		}
+		if rejection["request_id"] != id {
+			t.Errorf("Expected rejection to carry request ID %s, got %v", id, rejection["request_id"])
+		}
+		if request["error_code"] != ErrCodeBudgetExceeded || request["level"] != "WARN" {
+			t.Errorf("Unexpected request entry %v", request)
+		}
+	})
+}
+
+func TestResponseWriter_FlushAndUnwrap(t *testing.T) {
+	captureLogs(t)
+
+	var flushErr error
+	server := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		flushErr = http.NewResponseController(w).Flush()
+	}))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if flushErr != nil {
+		t.Errorf("Expected flush to reach the recorder, got %v", flushErr)
+	}
+	if !rr.Flushed {
+		t.Error("Expected recorder to be flushed")
+	}
+}
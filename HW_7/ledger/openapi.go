@@ -0,0 +1,779 @@
+package ledger
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.1.0"
+
+// openAPIComponents lists the DTOs published under components/schemas. Their
+// schemas are derived from the Go types, so adding a field to a DTO updates
+// the contract without touching this file.
+var openAPIComponents = []any{
+	CreateTransactionRequest{},
+	SplitRequest{},
+	TransactionResponse{},
+	SplitResponse{},
+	BatchTransactionsRequest{},
+	BatchTransactionsResponse{},
+	BatchResultResponse{},
+	SharingRequest{},
+	ParticipantRequest{},
+	SharingResponse{},
+	ParticipantResponse{},
+	AnomalyResponse{},
+	CreateBudgetRequest{},
+	BudgetResponse{},
+	ErrorResponse{},
+	HealthResponse{},
+	HealthCheckResult{},
+	BudgetForecastResponse{},
+	RecurringTransactionResponse{},
+	CreateGoalRequest{},
+	GoalResponse{},
+	SummaryResponse{},
+	TagReportResponse{},
+	AttachmentResponse{},
+	BalancesResponse{},
+	MemberBalanceResponse{},
+	SettlementResponse{},
+	CreateSettlementRequest{},
+	CreateReconciliationRequest{},
+	StatementLineRequest{},
+	StatementLineResponse{},
+	MatchRequest{},
+	MatchResponse{},
+	ReconciliationResponse{},
+	TransactionStatusRequest{},
+	ClosedPeriodResponse{},
+	ReopenPeriodRequest{},
+	PeriodEventResponse{},
+	EventResponse{},
+}
+
+type openAPIOperation struct {
+	Method  string
+	Path    string
+	Summary string
+	Query   any
+	Request any
+	// Upload names the multipart field of a file upload, used instead of a
+	// JSON Request.
+	Upload    string
+	Responses map[int]any
+}
+
+// openAPIBinary marks a response whose body is raw file content.
+type openAPIBinary struct{}
+
+// openAPIEventStream marks a server-sent event stream whose event data are
+// EventResponse objects.
+type openAPIEventStream struct{}
+
+var openAPIOperations = []openAPIOperation{
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/transactions",
+		Summary: "Create transaction",
+		Request: CreateTransactionRequest{},
+		Responses: map[int]any{
+			http.StatusCreated:             TransactionResponse{},
+			http.StatusBadRequest:          ErrorResponse{},
+			http.StatusConflict:            ErrorResponse{},
+			http.StatusUnprocessableEntity: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/transactions:batch",
+		Summary: "Create transactions in bulk",
+		Request: BatchTransactionsRequest{},
+		Responses: map[int]any{
+			http.StatusOK:                    BatchTransactionsResponse{},
+			http.StatusCreated:               BatchTransactionsResponse{},
+			http.StatusBadRequest:            ErrorResponse{},
+			http.StatusRequestEntityTooLarge: ErrorResponse{},
+			http.StatusUnprocessableEntity:   BatchTransactionsResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/events",
+		Summary: "Stream transaction and budget changes as server-sent events",
+		Query:   EventsQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         openAPIEventStream{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/transactions",
+		Summary: "List transactions",
+		Query:   TransactionFilter{},
+		Responses: map[int]any{
+			http.StatusOK:         []TransactionResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/anomalies",
+		Summary: "List transactions flagged as anomalous",
+		Query:   AnomalyQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         []TransactionResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/budgets",
+		Summary: "Create budget",
+		Request: CreateBudgetRequest{},
+		Responses: map[int]any{
+			http.StatusCreated:    BudgetResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/budgets",
+		Summary: "List budgets",
+		Responses: map[int]any{
+			http.StatusOK: []BudgetResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/budgets/{category}/forecast",
+		Summary: "Forecast end-of-month spending for a budget",
+		Query:   ForecastQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         BudgetForecastResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusNotFound:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/goals",
+		Summary: "Create savings goal",
+		Request: CreateGoalRequest{},
+		Responses: map[int]any{
+			http.StatusCreated:    GoalResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/goals",
+		Summary: "List savings goals with progress",
+		Query:   GoalQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         []GoalResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/goals/{id}",
+		Summary: "Get savings goal progress",
+		Query:   GoalQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         GoalResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusNotFound:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPut,
+		Path:    "/api/goals/{id}",
+		Summary: "Update savings goal",
+		Request: CreateGoalRequest{},
+		Responses: map[int]any{
+			http.StatusOK:         GoalResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusNotFound:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/api/goals/{id}",
+		Summary: "Delete savings goal",
+		Responses: map[int]any{
+			http.StatusNoContent: nil,
+			http.StatusNotFound:  ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/summary",
+		Summary: "Monthly summary of income, expenses, budgets and goals",
+		Query:   SummaryQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         SummaryResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/reports/tags",
+		Summary: "Total transactions by tag across categories",
+		Query:   TagReportQuery{},
+		Responses: map[int]any{
+			http.StatusOK:         []TagReportResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/balances",
+		Summary: "Balances between members with a settle-up plan",
+		Responses: map[int]any{
+			http.StatusOK: BalancesResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/balances/settlements",
+		Summary: "Record a settlement between two members",
+		Request: CreateSettlementRequest{},
+		Responses: map[int]any{
+			http.StatusCreated:    TransactionResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusConflict:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPut,
+		Path:    "/api/transactions/{id}/status",
+		Summary: "Mark transaction cleared or uncleared",
+		Request: TransactionStatusRequest{},
+		Responses: map[int]any{
+			http.StatusOK:         TransactionResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusNotFound:   ErrorResponse{},
+			http.StatusConflict:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/reconciliations",
+		Summary: "Start reconciling a bank statement",
+		Request: CreateReconciliationRequest{},
+		Responses: map[int]any{
+			http.StatusCreated:    ReconciliationResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusConflict:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/reconciliations",
+		Summary: "List reconciliations",
+		Responses: map[int]any{
+			http.StatusOK: []ReconciliationResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/reconciliations/{id}",
+		Summary: "Get reconciliation with unmatched items",
+		Responses: map[int]any{
+			http.StatusOK:       ReconciliationResponse{},
+			http.StatusNotFound: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/reconciliations/{id}/matches",
+		Summary: "Match a statement line to a transaction",
+		Request: MatchRequest{},
+		Responses: map[int]any{
+			http.StatusOK:         ReconciliationResponse{},
+			http.StatusBadRequest: ErrorResponse{},
+			http.StatusNotFound:   ErrorResponse{},
+			http.StatusConflict:   ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/api/reconciliations/{id}/matches/{line}",
+		Summary: "Unmatch a statement line",
+		Responses: map[int]any{
+			http.StatusOK:       ReconciliationResponse{},
+			http.StatusNotFound: ErrorResponse{},
+			http.StatusConflict: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/reconciliations/{id}/complete",
+		Summary: "Complete reconciliation and lock its period",
+		Responses: map[int]any{
+			http.StatusOK:       ReconciliationResponse{},
+			http.StatusNotFound: ErrorResponse{},
+			http.StatusConflict: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/periods",
+		Summary: "List closed periods",
+		Responses: map[int]any{
+			http.StatusOK: []ClosedPeriodResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/periods/audit",
+		Summary: "History of closed and reopened periods",
+		Responses: map[int]any{
+			http.StatusOK: []PeriodEventResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/periods/{period}/close",
+		Summary: "Close a month against changes (admin)",
+		Responses: map[int]any{
+			http.StatusOK:           ClosedPeriodResponse{},
+			http.StatusBadRequest:   ErrorResponse{},
+			http.StatusUnauthorized: ErrorResponse{},
+			http.StatusConflict:     ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/periods/{period}/reopen",
+		Summary: "Reopen a closed month (admin)",
+		Request: ReopenPeriodRequest{},
+		Responses: map[int]any{
+			http.StatusNoContent:    nil,
+			http.StatusBadRequest:   ErrorResponse{},
+			http.StatusUnauthorized: ErrorResponse{},
+			http.StatusConflict:     ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/api/transactions/{id}/attachments",
+		Summary: "Attach a receipt image or PDF",
+		Upload:  attachmentFormField,
+		Responses: map[int]any{
+			http.StatusCreated:               AttachmentResponse{},
+			http.StatusBadRequest:            ErrorResponse{},
+			http.StatusNotFound:              ErrorResponse{},
+			http.StatusConflict:              ErrorResponse{},
+			http.StatusRequestEntityTooLarge: ErrorResponse{},
+			http.StatusUnsupportedMediaType:  ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/transactions/{id}/attachments",
+		Summary: "List transaction attachments",
+		Responses: map[int]any{
+			http.StatusOK:       []AttachmentResponse{},
+			http.StatusNotFound: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/api/transactions/{id}/attachments/{attachment}",
+		Summary: "Download attachment",
+		Responses: map[int]any{
+			http.StatusOK:       openAPIBinary{},
+			http.StatusNotFound: ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/api/transactions/{id}/attachments/{attachment}",
+		Summary: "Delete attachment",
+		Responses: map[int]any{
+			http.StatusNoContent: nil,
+			http.StatusNotFound:  ErrorResponse{},
+			http.StatusConflict:  ErrorResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/health",
+		Summary: "Health check",
+		Responses: map[int]any{
+			http.StatusOK: map[string]string{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/health/live",
+		Summary: "Liveness probe",
+		Responses: map[int]any{
+			http.StatusOK: HealthResponse{},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/health/ready",
+		Summary: "Readiness probe with dependency checks",
+		Responses: map[int]any{
+			http.StatusOK:                 HealthResponse{},
+			http.StatusServiceUnavailable: HealthResponse{},
+		},
+	},
+}
+
+func OpenAPISpec() map[string]any {
+	schemas := make(map[string]any, len(openAPIComponents))
+	for _, component := range openAPIComponents {
+		t := reflect.TypeOf(component)
+		schemas[t.Name()] = structSchema(t)
+	}
+
+	paths := make(map[string]any)
+	for _, op := range openAPIOperations {
+		item, exists := paths[op.Path].(map[string]any)
+		if !exists {
+			item = make(map[string]any)
+			paths[op.Path] = item
+		}
+
+		responses := make(map[string]any, len(op.Responses))
+		for status, body := range op.Responses {
+			response := map[string]any{"description": http.StatusText(status)}
+			switch body.(type) {
+			case nil:
+			case openAPIBinary:
+				response["content"] = map[string]any{
+					"*/*": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+				}
+			case openAPIEventStream:
+				response["content"] = map[string]any{
+					"text/event-stream": map[string]any{"schema": map[string]any{
+						"type":        "string",
+						"description": "Server-sent events; each data field is an EventResponse.",
+					}},
+				}
+			default:
+				response["content"] = jsonContent(body)
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+
+		operation := map[string]any{
+			"summary":   op.Summary,
+			"responses": responses,
+		}
+		params := pathParameters(op.Path)
+		if op.Query != nil {
+			params = append(params, queryParameters(reflect.TypeOf(op.Query))...)
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(op.Request),
+			}
+		}
+		if op.Upload != "" {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"multipart/form-data": map[string]any{
+						"schema": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{op.Upload: map[string]any{"type": "string", "format": "binary"}},
+							"required":   []string{op.Upload},
+						},
+					},
+				},
+			}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "Ledger API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// pathParameters declares the {name} segments of a ServeMux pattern as
+// required string parameters.
+func pathParameters(path string) []any {
+	var params []any
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			params = append(params, map[string]any{
+				"name":     strings.TrimSuffix(name, "}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+	return params
+}
+
+func queryParameters(t reflect.Type) []any {
+	params := make([]any, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" {
+			continue
+		}
+
+		schema := schemaFor(field.Type)
+		if format := field.Tag.Get("format"); format != "" {
+			schema = map[string]any{"type": "string", "format": format}
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema["enum"] = strings.Split(enum, ",")
+		}
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": false,
+			"schema":   schema,
+		})
+	}
+	return params
+}
+
+func jsonContent(body any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{
+			"schema": schemaFor(reflect.TypeOf(body)),
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaFor(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if isComponent(t) {
+			return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		return structSchema(t)
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+func isComponent(t reflect.Type) bool {
+	for _, component := range openAPIComponents {
+		if reflect.TypeOf(component) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// structSchema maps exported fields by their json tag. Fields without
+// omitempty are required; `format` and `enum` tags refine string fields.
+func structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaFor(field.Type)
+		if format := field.Tag.Get("format"); format != "" {
+			schema["format"] = format
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema["enum"] = strings.Split(enum, ",")
+		}
+		properties[name] = schema
+
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	sort.Strings(required)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, OpenAPISpec())
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Ledger API</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2rem auto; color: #222; }
+.op { border: 1px solid #ddd; border-radius: 4px; margin: 1rem 0; padding: 0.5rem 1rem; }
+.method { display: inline-block; min-width: 4rem; font-weight: bold; }
+pre { background: #f6f6f6; padding: 0.5rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Ledger API</h1>
+<p>Machine-readable contract: <a href="/openapi.json">/openapi.json</a></p>
+<h2>Endpoints</h2>
+{{range .Operations}}
+<div class="op">
+<h3><span class="method">{{.Method}}</span> {{.Path}}</h3>
+<p>{{.Summary}}</p>
+{{if .Request}}<p>Request: <a href="#{{.Request}}">{{.Request}}</a></p>{{end}}
+<ul>
+{{range .Responses}}<li>{{.Status}}: {{if .Schema}}<a href="#{{.Schema}}">{{.Schema}}</a>{{else}}{{.Description}}{{end}}</li>
+{{end}}
+</ul>
+</div>
+{{end}}
+<h2>Schemas</h2>
+{{range .Schemas}}
+<h3 id="{{.Name}}">{{.Name}}</h3>
+<pre>{{.JSON}}</pre>
+{{end}}
+</body>
+</html>
+`))
+
+type docsResponse struct {
+	Status      string
+	Schema      string
+	Description string
+}
+
+type docsOperation struct {
+	Method    string
+	Path      string
+	Summary   string
+	Request   string
+	Responses []docsResponse
+}
+
+type docsSchema struct {
+	Name string
+	JSON string
+}
+
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ops := make([]docsOperation, 0, len(openAPIOperations))
+	for _, op := range openAPIOperations {
+		doc := docsOperation{Method: op.Method, Path: op.Path, Summary: op.Summary}
+		if op.Request != nil {
+			doc.Request = componentName(reflect.TypeOf(op.Request))
+		}
+
+		statuses := make([]int, 0, len(op.Responses))
+		for status := range op.Responses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			response := docsResponse{Status: strconv.Itoa(status) + " " + http.StatusText(status), Description: "empty body"}
+			if _, ok := op.Responses[status].(openAPIBinary); ok {
+				response.Description = "file content"
+			} else if _, ok := op.Responses[status].(openAPIEventStream); ok {
+				response.Description = "event stream of EventResponse"
+			} else if t := reflect.TypeOf(op.Responses[status]); t != nil {
+				response.Schema = componentName(t)
+				response.Description = t.String()
+			}
+			doc.Responses = append(doc.Responses, response)
+		}
+		ops = append(ops, doc)
+	}
+
+	schemas := make([]docsSchema, 0, len(openAPIComponents))
+	for _, component := range openAPIComponents {
+		t := reflect.TypeOf(component)
+		data, _ := json.MarshalIndent(structSchema(t), "", "  ")
+		schemas = append(schemas, docsSchema{Name: t.Name(), JSON: string(data)})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	docsTemplate.Execute(w, struct {
+		Operations []docsOperation
+		Schemas    []docsSchema
+	}{ops, schemas})
+}
+
+// componentName returns the component a type refers to, looking through
+// slices, or "" when the type is not a published DTO.
+func componentName(t reflect.Type) string {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if isComponent(t) {
+		return t.Name()
+	}
+	return ""
+}
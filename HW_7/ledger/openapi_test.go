@@ -0,0 +1,245 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	OpenAPIHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var spec map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+	if spec["openapi"] != "3.1.0" {
+		t.Fatalf("Expected openapi 3.1.0, got %v", spec["openapi"])
+	}
+	return spec
+}
+
+func responseSchema(t *testing.T, spec map[string]any, method, path string, status int) map[string]any {
+	t.Helper()
+
+	path, _, _ = strings.Cut(path, "?")
+	paths := spec["paths"].(map[string]any)
+	op, ok := paths[path].(map[string]any)[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		t.Fatalf("Spec has no operation %s %s", method, path)
+	}
+	resp, ok := op["responses"].(map[string]any)[strconv.Itoa(status)].(map[string]any)
+	if !ok {
+		t.Fatalf("Spec has no %d response for %s %s", status, method, path)
+	}
+	content := resp["content"].(map[string]any)["application/json"].(map[string]any)
+	return content["schema"].(map[string]any)
+}
+
+func validateSchema(spec map[string]any, schema map[string]any, value any, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := spec["components"].(map[string]any)["schemas"].(map[string]any)
+		return validateSchema(spec, components[name].(map[string]any), value, at)
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, value)
+		}
+		for _, name := range asSlice(schema["required"]) {
+			if _, exists := obj[name.(string)]; !exists {
+				return fmt.Errorf("%s: missing required field %q", at, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, fieldValue := range obj {
+			fieldSchema, exists := properties[name].(map[string]any)
+			if !exists {
+				extra, _ := schema["additionalProperties"].(map[string]any)
+				if extra == nil {
+					return fmt.Errorf("%s: unexpected field %q", at, name)
+				}
+				fieldSchema = extra
+			}
+			if err := validateSchema(spec, fieldSchema, fieldValue, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := validateSchema(spec, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, value)
+		}
+		if enum := asSlice(schema["enum"]); len(enum) > 0 && !containsValue(enum, str) {
+			return fmt.Errorf("%s: %q not in enum %v", at, str, enum)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: invalid date-time %q", at, str)
+			}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func containsValue(values []any, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
+
+func withPathValue(name, value string, handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue(name, value)
+		handle(w, r)
+	}
+}
+
+func withBearer(token string, handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+		handle(w, r)
+	}
+}
+
+func TestOpenAPI_HandlerResponsesMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+	handler := NewHandler(NewLedger())
+	handler.SetAdminTokens(map[string]string{"s3cret": "alice"})
+
+	starting, ready := NewHealth(), NewHealth()
+	ready.Register("storage", func(context.Context) error { return nil })
+	ready.SetReady()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		handle http.HandlerFunc
+		status int
+	}{
+		{"create budget", "POST", "/api/budgets", `{"category":"food","limit":1000}`, handler.CreateBudgetHandler, http.StatusCreated},
+		{"invalid budget", "POST", "/api/budgets", `{"category":"food","limit":-1}`, handler.CreateBudgetHandler, http.StatusBadRequest},
+		{"create transaction", "POST", "/api/transactions", `{"amount":100,"category":"food","description":"groceries","date":"2024-01-15","type":"expense"}`, handler.CreateTransactionHandler, http.StatusCreated},
+		{"create split transaction", "POST", "/api/transactions", `{"amount":90,"date":"2024-01-15","type":"expense","splits":[{"category":"food","amount":60},{"category":"household","amount":30,"note":"detergent"}]}`, handler.CreateTransactionHandler, http.StatusCreated},
+		{"create tagged transaction", "POST", "/api/transactions", `{"amount":40,"category":"transport","date":"2024-01-15","type":"expense","tags":["vacation-2024"],"metadata":{"merchant":"Rail"}}`, handler.CreateTransactionHandler, http.StatusCreated},
+		{"budget exceeded", "POST", "/api/transactions", `{"amount":5000,"category":"food","date":"2024-01-16","type":"expense"}`, handler.CreateTransactionHandler, http.StatusConflict},
+		{"invalid transaction", "POST", "/api/transactions", `{"amount":100,"category":"food","date":"15-01-2024","type":"expense"}`, handler.CreateTransactionHandler, http.StatusBadRequest},
+		{"list transactions", "GET", "/api/transactions", "", handler.ListTransactionsHandler, http.StatusOK},
+		{"list anomalies", "GET", "/api/anomalies", "", handler.ListAnomaliesHandler, http.StatusOK},
+		{"list budgets", "GET", "/api/budgets", "", handler.ListBudgetsHandler, http.StatusOK},
+		{"forecast", "GET", "/api/budgets/{category}/forecast", "", withPathValue("category", "food", handler.ForecastBudgetHandler), http.StatusOK},
+		{"forecast unknown budget", "GET", "/api/budgets/{category}/forecast", "", withPathValue("category", "rent", handler.ForecastBudgetHandler), http.StatusNotFound},
+		{"create goal", "POST", "/api/goals", `{"name":"Car","target":1000,"category":"savings","deadline":"2030-01-01"}`, handler.CreateGoalHandler, http.StatusCreated},
+		{"invalid goal", "POST", "/api/goals", `{"name":"Car","target":0,"category":"savings","deadline":"2030-01-01"}`, handler.CreateGoalHandler, http.StatusBadRequest},
+		{"list goals", "GET", "/api/goals", "", handler.ListGoalsHandler, http.StatusOK},
+		{"unknown goal", "GET", "/api/goals/{id}", "", withPathValue("id", "boat", handler.GetGoalHandler), http.StatusNotFound},
+		{"summary", "GET", "/api/summary", "", handler.SummaryHandler, http.StatusOK},
+		{"tag report", "GET", "/api/reports/tags", "", handler.TagReportHandler, http.StatusOK},
+		{"create shared transaction", "POST", "/api/transactions", `{"amount":90,"category":"dining","date":"2024-01-15","type":"expense","sharing":{"paid_by":"ann","method":"shares","participants":[{"member":"ann","shares":2},{"member":"bob","shares":1}]}}`, handler.CreateTransactionHandler, http.StatusCreated},
+		{"balances", "GET", "/api/balances", "", handler.BalancesHandler, http.StatusOK},
+		{"record settlement", "POST", "/api/balances/settlements", `{"from":"bob","to":"ann","amount":30,"date":"2024-01-20"}`, handler.CreateSettlementHandler, http.StatusCreated},
+		{"settle with oneself", "POST", "/api/balances/settlements", `{"from":"bob","to":"bob","amount":30}`, handler.CreateSettlementHandler, http.StatusBadRequest},
+		{"create reconciliation", "POST", "/api/reconciliations", `{"from":"2024-01-01","to":"2024-01-31","lines":[{"date":"2024-01-15","amount":-100,"description":"GROCERIES"},{"date":"2024-01-20","amount":-7}]}`, handler.CreateReconciliationHandler, http.StatusCreated},
+		{"invalid reconciliation", "POST", "/api/reconciliations", `{"from":"2024-01-31","to":"2024-01-01","lines":[]}`, handler.CreateReconciliationHandler, http.StatusBadRequest},
+		{"list reconciliations", "GET", "/api/reconciliations", "", handler.ListReconciliationsHandler, http.StatusOK},
+		{"unknown reconciliation", "GET", "/api/reconciliations/{id}", "", withPathValue("id", "missing", handler.GetReconciliationHandler), http.StatusNotFound},
+		{"complete unknown reconciliation", "POST", "/api/reconciliations/{id}/complete", "", withPathValue("id", "missing", handler.CompleteReconciliationHandler), http.StatusNotFound},
+		{"status of unknown transaction", "PUT", "/api/transactions/{id}/status", `{"status":"cleared"}`, withPathValue("id", "missing", handler.SetTransactionStatusHandler), http.StatusNotFound},
+		{"close without admin", "POST", "/api/periods/{period}/close", "", withPathValue("period", "2023-01", handler.ClosePeriodHandler), http.StatusUnauthorized},
+		{"close period", "POST", "/api/periods/{period}/close", "", withBearer("s3cret", withPathValue("period", "2023-01", handler.ClosePeriodHandler)), http.StatusOK},
+		{"close closed period", "POST", "/api/periods/{period}/close", "", withBearer("s3cret", withPathValue("period", "2023-01", handler.ClosePeriodHandler)), http.StatusConflict},
+		{"reopen without admin", "POST", "/api/periods/{period}/reopen", `{"reason":"fix"}`, withPathValue("period", "2023-01", handler.ReopenPeriodHandler), http.StatusUnauthorized},
+		{"list closed periods", "GET", "/api/periods", "", handler.ListClosedPeriodsHandler, http.StatusOK},
+		{"period audit", "GET", "/api/periods/audit", "", handler.PeriodAuditHandler, http.StatusOK},
+		{"atomic batch", "POST", "/api/transactions:batch", `{"mode":"atomic","transactions":[{"amount":5,"category":"food","date":"2024-01-16","type":"expense"}]}`, handler.CreateTransactionsBatchHandler, http.StatusCreated},
+		{"rejected batch", "POST", "/api/transactions:batch", `{"mode":"atomic","transactions":[{"amount":5,"category":"food","date":"2024-01-16","type":"expense"},{"amount":-1,"category":"food","date":"2024-01-16","type":"expense"}]}`, handler.CreateTransactionsBatchHandler, http.StatusUnprocessableEntity},
+		{"best effort batch", "POST", "/api/transactions:batch", `{"mode":"best_effort","transactions":[{"amount":5,"category":"food","date":"bad","type":"expense"}]}`, handler.CreateTransactionsBatchHandler, http.StatusOK},
+		{"invalid batch mode", "POST", "/api/transactions:batch", `{"mode":"some","transactions":[]}`, handler.CreateTransactionsBatchHandler, http.StatusBadRequest},
+		{"invalid last event ID", "GET", "/api/events?last_event_id=x", "", handler.EventsHandler, http.StatusBadRequest},
+		{"list attachments", "GET", "/api/transactions/{id}/attachments", "", withPathValue("id", "missing", handler.ListAttachmentsHandler), http.StatusNotFound},
+		{"upload without multipart", "POST", "/api/transactions/{id}/attachments", "", withPathValue("id", "missing", handler.UploadAttachmentHandler), http.StatusBadRequest},
+		{"live", "GET", "/health/live", "", ready.LiveHandler, http.StatusOK},
+		{"ready", "GET", "/health/ready", "", ready.ReadyHandler, http.StatusOK},
+		{"not ready", "GET", "/health/ready", "", starting.ReadyHandler, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			tt.handle(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+
+			var body any
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			schema := responseSchema(t, spec, tt.method, tt.path, tt.status)
+			if err := validateSchema(spec, schema, body, "$"); err != nil {
+				t.Errorf("Response does not match spec: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenAPI_DocsPage(t *testing.T) {
+	req := httptest.NewRequest("GET", "/docs", nil)
+	rr := httptest.NewRecorder()
+	DocsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	for _, want := range []string{"/openapi.json", "/api/transactions", "TransactionResponse", "BudgetResponse"} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("Expected docs page to mention %q", want)
+		}
+	}
+}
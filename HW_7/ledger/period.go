@@ -0,0 +1,311 @@
+package ledger
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPeriodClosed    = errors.New("period is closed")
+	ErrPeriodNotClosed = errors.New("period is not closed")
+)
+
+const (
+	PeriodClosed   = "closed"
+	PeriodReopened = "reopened"
+)
+
+type ClosedPeriod struct {
+	Period   string    `json:"period"`
+	ClosedAt time.Time `json:"closed_at"`
+	ClosedBy string    `json:"closed_by,omitempty"`
+}
+
+// PeriodEvent is an audit record of a period being closed or reopened.
+type PeriodEvent struct {
+	Period string    `json:"period"`
+	Action string    `json:"action"`
+	Actor  string    `json:"actor,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ParsePeriod parses a month in YYYY-MM form.
+func ParsePeriod(s string) (string, error) {
+	month, err := time.Parse(periodLayout, s)
+	if err != nil {
+		return "", errors.New("invalid period, use YYYY-MM")
+	}
+	return month.Format(periodLayout), nil
+}
+
+// ClosePeriod locks the month against adding, editing or deleting
+// transactions dated in it. Months that have not started cannot be closed.
+func (l *Ledger) ClosePeriod(period, actor string) (*ClosedPeriod, error) {
+	if period > periodKey(today()) {
+		return nil, errors.New("cannot close a future period")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, closed := l.closedPeriods[period]; closed {
+		return nil, fmt.Errorf("%w: %s", ErrPeriodClosed, period)
+	}
+
+	closed := &ClosedPeriod{Period: period, ClosedAt: time.Now().UTC(), ClosedBy: actor}
+	l.closedPeriods[period] = closed
+	l.periodAudit = append(l.periodAudit, PeriodEvent{Period: period, Action: PeriodClosed, Actor: actor, At: closed.ClosedAt})
+	return closed, nil
+}
+
+// ReopenPeriod unlocks a closed month. Callers are expected to have checked
+// that actor may do so; the reason is kept in the audit log.
+func (l *Ledger) ReopenPeriod(period, actor, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("reason cannot be empty")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, closed := l.closedPeriods[period]; !closed {
+		return fmt.Errorf("%w: %s", ErrPeriodNotClosed, period)
+	}
+
+	delete(l.closedPeriods, period)
+	l.periodAudit = append(l.periodAudit, PeriodEvent{Period: period, Action: PeriodReopened, Actor: actor, Reason: reason, At: time.Now().UTC()})
+	return nil
+}
+
+// ClosedPeriods returns the closed months in order.
+func (l *Ledger) ClosedPeriods() []ClosedPeriod {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.sortedClosedPeriods()
+}
+
+func (l *Ledger) sortedClosedPeriods() []ClosedPeriod {
+	periods := make([]ClosedPeriod, 0, len(l.closedPeriods))
+	for _, closed := range l.closedPeriods {
+		periods = append(periods, *closed)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Period < periods[j].Period })
+	return periods
+}
+
+// PeriodAudit returns every close and reopen, oldest first.
+func (l *Ledger) PeriodAudit() []PeriodEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return slices.Clone(l.periodAudit)
+}
+
+// checkClosed fails if old, when given, or any of the dates falls in a
+// closed month.
+func (l *Ledger) checkClosed(old *Transaction, dates ...time.Time) error {
+	if old != nil {
+		dates = append(dates, old.Date)
+	}
+	for _, date := range dates {
+		period := periodKey(date)
+		if _, closed := l.closedPeriods[period]; closed {
+			return fmt.Errorf("%w: %s", ErrPeriodClosed, period)
+		}
+	}
+	return nil
+}
+
+// checkEditable guards every change to the books: transactions in closed or
+// reconciled periods stay as they are.
+func (l *Ledger) checkEditable(old *Transaction, dates ...time.Time) error {
+	if err := l.checkClosed(old, dates...); err != nil {
+		return err
+	}
+	return l.checkReconciled(old, dates...)
+}
+
+// ParseAdminTokens reads comma-separated name:token pairs. The name is
+// recorded as the actor of admin operations.
+func ParseAdminTokens(spec string) (map[string]string, error) {
+	return parseNamedTokens(spec, "admin token")
+}
+
+// parseNamedTokens reads comma-separated name:token pairs into a map from
+// token to name.
+func parseNamedTokens(spec, kind string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("invalid %s %q, use name:token", kind, pair)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("%s for %s is not unique", kind, name)
+		}
+		tokens[token] = name
+	}
+	return tokens, nil
+}
+
+// SetAdminTokens sets the bearer tokens, keyed to admin names, accepted for
+// admin operations. Without any, those operations are refused.
+func (h *Handler) SetAdminTokens(admins map[string]string) {
+	h.admins = admins
+}
+
+// admin returns the name of the admin authenticated by the request's bearer
+// token.
+func (h *Handler) admin(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	for candidate, name := range h.admins {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// requireAdmin is admin for handlers, answering 401 when the request is not
+// from an admin.
+func (h *Handler) requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	actor, ok := h.admin(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "admin token required")
+	}
+	return actor, ok
+}
+
+type ClosedPeriodResponse struct {
+	Period   string    `json:"period"`
+	ClosedAt time.Time `json:"closed_at"`
+	ClosedBy string    `json:"closed_by,omitempty"`
+}
+
+type ReopenPeriodRequest struct {
+	Reason string `json:"reason"`
+}
+
+type PeriodEventResponse struct {
+	Period string    `json:"period"`
+	Action string    `json:"action" enum:"closed,reopened"`
+	Actor  string    `json:"actor,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ClosePeriodHandler closes a month for an admin. Closing is admin-only like
+// reopening, or anyone could lock a month only admins can unlock.
+func (h *Handler) ClosePeriodHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	period, err := ParsePeriod(r.PathValue("period"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actor, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	closed, err := h.ledger.ClosePeriod(period, actor)
+	switch {
+	case errors.Is(err, ErrPeriodClosed):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
+		return
+	}
+	Logger(r.Context()).Info("period closed", "period", period, "actor", actor)
+
+	writeJSON(w, http.StatusOK, ClosedPeriodResponse{Period: closed.Period, ClosedAt: closed.ClosedAt, ClosedBy: closed.ClosedBy})
+}
+
+func (h *Handler) ReopenPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	actor, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := ParsePeriod(r.PathValue("period"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req ReopenPeriodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	err = h.ledger.ReopenPeriod(period, actor, req.Reason)
+	switch {
+	case errors.Is(err, ErrPeriodNotClosed):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
+		return
+	}
+	Logger(r.Context()).Warn("period reopened", "period", period, "actor", actor, "reason", req.Reason)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListClosedPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	periods := h.ledger.ClosedPeriods()
+	response := make([]ClosedPeriodResponse, len(periods))
+	for i, closed := range periods {
+		response[i] = ClosedPeriodResponse{Period: closed.Period, ClosedAt: closed.ClosedAt, ClosedBy: closed.ClosedBy}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) PeriodAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	events := h.ledger.PeriodAudit()
+	response := make([]PeriodEventResponse, len(events))
+	for i, e := range events {
+		response[i] = PeriodEventResponse{Period: e.Period, Action: e.Action, Actor: e.Actor, Reason: e.Reason, At: e.At}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
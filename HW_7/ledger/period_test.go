@@ -0,0 +1,142 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLedger_ClosePeriod(t *testing.T) {
+	ledger := NewLedger()
+	rent := &Transaction{ID: "rent", Amount: 900, Category: "housing", Date: date("2024-04-01"), Type: "expense"}
+	if err := ledger.AddTransaction(rent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := ledger.ClosePeriod("2024-04", "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := ledger.ClosePeriod("2024-04", "alice"); !errors.Is(err, ErrPeriodClosed) {
+		t.Errorf("Expected %v closing twice, got %v", ErrPeriodClosed, err)
+	}
+	if _, err := ledger.ClosePeriod("2999-01", "alice"); err == nil {
+		t.Error("Expected error closing a future period")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"add", ledger.AddTransaction(&Transaction{ID: "late", Amount: 10, Category: "food", Date: date("2024-04-30"), Type: "expense"})},
+		{"edit", ledger.UpdateTransaction(&Transaction{ID: "rent", Amount: 950, Category: "housing", Date: date("2024-04-01"), Type: "expense"})},
+		{"move out", ledger.UpdateTransaction(&Transaction{ID: "rent", Amount: 900, Category: "housing", Date: date("2024-05-01"), Type: "expense"})},
+		{"delete", ledger.DeleteTransaction("rent")},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, ErrPeriodClosed) {
+			t.Errorf("%s: expected %v, got %v", tt.name, ErrPeriodClosed, tt.err)
+		}
+	}
+	if err := ledger.AddTransaction(&Transaction{ID: "may", Amount: 10, Category: "food", Date: date("2024-05-01"), Type: "expense"}); err != nil {
+		t.Errorf("Expected other periods to stay open, got %v", err)
+	}
+
+	if err := ledger.ReopenPeriod("2024-04", "bob", " "); err == nil {
+		t.Error("Expected a reason to be required")
+	}
+	if err := ledger.ReopenPeriod("2024-04", "bob", "late invoice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ledger.ReopenPeriod("2024-04", "bob", "again"); !errors.Is(err, ErrPeriodNotClosed) {
+		t.Errorf("Expected %v, got %v", ErrPeriodNotClosed, err)
+	}
+	if err := ledger.DeleteTransaction("rent"); err != nil {
+		t.Errorf("Expected reopened period to accept changes, got %v", err)
+	}
+
+	audit := ledger.PeriodAudit()
+	if len(audit) != 2 || audit[0].Action != PeriodClosed || audit[1].Actor != "bob" || audit[1].Reason != "late invoice" {
+		t.Errorf("Unexpected audit log %+v", audit)
+	}
+}
+
+func TestParseAdminTokens(t *testing.T) {
+	admins, err := ParseAdminTokens("alice:s3cret, bob:t0ken")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if admins["s3cret"] != "alice" || admins["t0ken"] != "bob" {
+		t.Errorf("Unexpected admins %v", admins)
+	}
+
+	for _, spec := range []string{"alice", "alice:", ":s3cret", "alice:x,bob:x"} {
+		if _, err := ParseAdminTokens(spec); err == nil {
+			t.Errorf("%q: expected error, got nil", spec)
+		}
+	}
+}
+
+func TestPeriodHandlers(t *testing.T) {
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+	handler.SetAdminTokens(map[string]string{"s3cret": "alice"})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	mux.HandleFunc("GET /api/periods/audit", handler.PeriodAuditHandler)
+	mux.HandleFunc("POST /api/periods/{period}/close", handler.ClosePeriodHandler)
+	mux.HandleFunc("POST /api/periods/{period}/reopen", handler.ReopenPeriodHandler)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("POST", "/api/periods/2024-03/close", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d closing without a token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if rr := do("POST", "/api/periods/2024-03/close", "s3cret", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr := do("POST", "/api/transactions", "", `{"amount":10,"category":"food","date":"2024-03-15","type":"expense"}`)
+	var errResp ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errResp)
+	if rr.Code != http.StatusConflict || errResp.Code != ErrCodePeriodLocked {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, ErrCodePeriodLocked, rr.Code, errResp.Code)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"invalid period", "/api/periods/March/close", "s3cret", "", http.StatusBadRequest},
+		{"reopen without token", "/api/periods/2024-03/reopen", "", `{"reason":"fix"}`, http.StatusUnauthorized},
+		{"reopen with wrong token", "/api/periods/2024-03/reopen", "guess", `{"reason":"fix"}`, http.StatusUnauthorized},
+		{"reopen without reason", "/api/periods/2024-03/reopen", "s3cret", `{}`, http.StatusBadRequest},
+		{"reopen open period", "/api/periods/2024-02/reopen", "s3cret", `{"reason":"fix"}`, http.StatusConflict},
+		{"reopen", "/api/periods/2024-03/reopen", "s3cret", `{"reason":"missed receipt"}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if rr := do("POST", tt.path, tt.token, tt.body); rr.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, rr.Code)
+		}
+	}
+
+	rr = do("GET", "/api/periods/audit", "", "")
+	var audit []PeriodEventResponse
+	json.Unmarshal(rr.Body.Bytes(), &audit)
+	if len(audit) != 2 || audit[1].Action != PeriodReopened || audit[1].Actor != "alice" || audit[1].Reason != "missed receipt" {
+		t.Errorf("Unexpected audit log %+v", audit)
+	}
+}
@@ -0,0 +1,254 @@
+package ledger
+
+import (
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit allows Requests per Period, refilled continuously, with bursts of
+// up to Requests.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. The in-memory store serves a single
+// instance; a shared implementation lets several instances enforce one quota.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) RateLimitResult
+}
+
+type RateLimitConfig struct {
+	Default RateLimit
+	// Routes overrides Default per route, keyed by its pattern with the
+	// method, e.g. "PUT /api/transactions/{id}/status".
+	Routes map[string]RateLimit
+	// Groups puts routes in a named bucket shared by the whole group, so a
+	// quota cannot be multiplied by switching routes. Other routes each have
+	// their own bucket.
+	Groups map[string]string
+	// Mux resolves requests to their route when the middleware wraps it;
+	// requests it does not route share one bucket per method.
+	Mux *http.ServeMux
+	// KeyFunc identifies the caller; defaults to the client IP.
+	KeyFunc func(r *http.Request) string
+	Store   RateLimitStore
+}
+
+// TransactionRoutes are the routes that create transactions. The default
+// config gives them one shared, stricter limit.
+var TransactionRoutes = []string{
+	"POST /api/transactions",
+	"POST /api/transactions:batch",
+	"POST /api/balances/settlements",
+	"POST /{$}",
+}
+
+func DefaultRateLimitConfig() RateLimitConfig {
+	config := RateLimitConfig{
+		Default: RateLimit{Requests: 120, Period: time.Minute},
+		Routes: map[string]RateLimit{
+			// Probes come from the orchestrator and must never be throttled.
+			"GET /health/live":  {},
+			"GET /health/ready": {},
+		},
+		Groups: make(map[string]string),
+	}
+	config.LimitTransactions(RateLimit{Requests: 30, Period: time.Minute})
+	return config
+}
+
+// LimitTransactions sets the limit of the TransactionRoutes, which share one
+// bucket.
+func (c *RateLimitConfig) LimitTransactions(limit RateLimit) {
+	if c.Routes == nil {
+		c.Routes = make(map[string]RateLimit)
+	}
+	if c.Groups == nil {
+		c.Groups = make(map[string]string)
+	}
+	for _, route := range TransactionRoutes {
+		c.Routes[route] = limit
+		c.Groups[route] = "transactions"
+	}
+}
+
+func RateLimitMiddleware(config RateLimitConfig) func(http.Handler) http.Handler {
+	if config.KeyFunc == nil {
+		config.KeyFunc = ClientKey
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeOf(r, config.Mux)
+			limit, exists := config.Routes[route]
+			if !exists {
+				limit = config.Default
+			}
+			if limit.Requests <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bucket := route
+			if group, exists := config.Groups[route]; exists {
+				bucket = group
+			}
+			result := config.Store.Take(config.KeyFunc(r)+"|"+bucket, limit)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeOf names the route serving r by its pattern rather than its path, so
+// requests for different IDs share a bucket.
+func routeOf(r *http.Request, mux *http.ServeMux) string {
+	pattern := r.Pattern
+	if pattern == "" && mux != nil {
+		_, pattern = mux.Handler(r)
+	}
+	if pattern == "" {
+		return r.Method + " *"
+	}
+	if _, _, hasMethod := strings.Cut(pattern, " "); !hasMethod {
+		pattern = r.Method + " " + pattern
+	}
+	return pattern
+}
+
+// ClientKey identifies the caller by IP address.
+func ClientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ParseAPIKeys reads comma-separated name:key pairs naming the clients that
+// rate limits are tracked for by API key.
+func ParseAPIKeys(spec string) (map[string]string, error) {
+	return parseNamedTokens(spec, "API key")
+}
+
+// APIKeyClientKey identifies callers presenting one of keys, which map API
+// keys to client names, by that name and everyone else by IP address. Unknown
+// keys are ignored, so rotating them does not earn fresh buckets.
+func APIKeyClientKey(keys map[string]string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			for candidate, name := range keys {
+				if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+					return "key:" + name
+				}
+			}
+		}
+		return ClientKey(r)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	capacity float64
+	perToken time.Duration
+}
+
+func (b *tokenBucket) refill(now time.Time) float64 {
+	return math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()/b.perToken.Seconds())
+}
+
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// maxIdleBuckets bounds memory: past it, buckets that have refilled
+// completely are dropped, since a fresh bucket behaves identically.
+const maxIdleBuckets = 10000
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) RateLimitResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(limit.Requests)
+	perToken := limit.Period / time.Duration(limit.Requests)
+
+	if len(s.buckets) > maxIdleBuckets {
+		s.sweep(now)
+	}
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.capacity = capacity
+	bucket.perToken = perToken
+
+	bucket.tokens = bucket.refill(now)
+	bucket.last = now
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.ResetAfter = time.Duration((capacity - bucket.tokens) * float64(perToken))
+	return result
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if bucket.refill(now) >= bucket.capacity {
+			delete(s.buckets, key)
+		}
+	}
+}
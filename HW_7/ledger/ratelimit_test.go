@@ -0,0 +1,134 @@
+package ledger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/budgets", ok)
+	mux.HandleFunc("POST /api/transactions", ok)
+	mux.HandleFunc("POST /api/transactions:batch", ok)
+	mux.HandleFunc("PUT /api/transactions/{id}/status", ok)
+	mux.HandleFunc("GET /health", ok)
+	handler := LoggingMiddleware(RateLimitMiddleware(RateLimitConfig{
+		Default: RateLimit{Requests: 3, Period: time.Minute},
+		Routes: map[string]RateLimit{
+			"POST /api/transactions":       {Requests: 1, Period: time.Minute},
+			"POST /api/transactions:batch": {Requests: 1, Period: time.Minute},
+			"GET /health":                  {},
+		},
+		Groups: map[string]string{
+			"POST /api/transactions":       "transactions",
+			"POST /api/transactions:batch": "transactions",
+		},
+		Mux:     mux,
+		KeyFunc: APIKeyClientKey(map[string]string{"alice-key": "alice", "bob-key": "bob"}),
+		Store:   store,
+	})(mux))
+
+	send := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			rr := send("GET", "/api/budgets", "alice-key")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected status %d, got %d", i, http.StatusOK, rr.Code)
+			}
+			if got, want := rr.Header().Get("RateLimit-Remaining"), strconv.Itoa(2-i); got != want {
+				t.Errorf("Request %d: expected RateLimit-Remaining %s, got %s", i, want, got)
+			}
+		}
+
+		rr := send("GET", "/api/budgets", "alice-key")
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+		if got := rr.Header().Get("Retry-After"); got != "20" {
+			t.Errorf("Expected Retry-After 20, got %s", got)
+		}
+		if got := rr.Header().Get("RateLimit-Limit"); got != "3" {
+			t.Errorf("Expected RateLimit-Limit 3, got %s", got)
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		if rr := send("GET", "/api/budgets", "bob-key"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d for another API key, got %d", http.StatusOK, rr.Code)
+		}
+		if rr := send("GET", "/api/budgets", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d for an IP without API key, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("unknown keys share the IP's bucket", func(t *testing.T) {
+		for i, key := range []string{"x1", "x2"} {
+			if rr := send("GET", "/api/budgets", key); rr.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected status %d, got %d", i, http.StatusOK, rr.Code)
+			}
+		}
+		if rr := send("GET", "/api/budgets", "x3"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected rotating keys to be limited, got %d", rr.Code)
+		}
+	})
+
+	t.Run("IDs share the route's bucket", func(t *testing.T) {
+		for i := range 3 {
+			if rr := send("PUT", "/api/transactions/"+strconv.Itoa(i)+"/status", "bob-key"); rr.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected status %d, got %d", i, http.StatusOK, rr.Code)
+			}
+		}
+		if rr := send("PUT", "/api/transactions/other/status", "bob-key"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected a new ID to be limited, got %d", rr.Code)
+		}
+	})
+
+	t.Run("route override", func(t *testing.T) {
+		if rr := send("POST", "/api/transactions", "alice-key"); rr.Code != http.StatusOK {
+			t.Errorf("Expected first POST to pass, got %d", rr.Code)
+		}
+		if rr := send("POST", "/api/transactions", "alice-key"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected second POST to be limited, got %d", rr.Code)
+		}
+		if rr := send("POST", "/api/transactions:batch", "alice-key"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected a batch to share the transactions bucket, got %d", rr.Code)
+		}
+	})
+
+	t.Run("unlimited route", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			if rr := send("GET", "/health", "alice-key"); rr.Code != http.StatusOK {
+				t.Fatalf("Expected health checks to be unlimited, got %d", rr.Code)
+			}
+		}
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(20 * time.Second)
+		if rr := send("GET", "/api/budgets", "alice-key"); rr.Code != http.StatusOK {
+			t.Errorf("Expected a token after refill, got %d", rr.Code)
+		}
+		if rr := send("GET", "/api/budgets", "alice-key"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected only one refilled token, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,765 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrReconciliationNotFound  = errors.New("reconciliation not found")
+	ErrReconciliationCompleted = errors.New("reconciliation already completed")
+	ErrStatementLineNotFound   = errors.New("statement line not found")
+	ErrAlreadyMatched          = errors.New("statement line or transaction already matched")
+	ErrUnmatchedLines          = errors.New("statement has unmatched lines")
+	ErrPeriodReconciled        = errors.New("period is reconciled")
+)
+
+const (
+	StatusUncleared  = "uncleared"
+	StatusCleared    = "cleared"
+	StatusReconciled = "reconciled"
+)
+
+type StatementLine struct {
+	ID   string    `json:"id"`
+	Date time.Time `json:"date"`
+	// Amount is signed as on the statement: deposits are positive, payments
+	// negative.
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+}
+
+type Match struct {
+	LineID        string  `json:"line_id"`
+	TransactionID string  `json:"transaction_id"`
+	Similarity    float64 `json:"similarity"`
+}
+
+// Reconciliation compares a bank statement covering From to To, inclusive,
+// with the ledger. Completing it marks the matched transactions reconciled
+// and locks the period against edits.
+type Reconciliation struct {
+	ID          string          `json:"id"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Lines       []StatementLine `json:"lines"`
+	Matches     []Match         `json:"matches,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+func (r *Reconciliation) Completed() bool {
+	return r.CompletedAt != nil
+}
+
+func (r *Reconciliation) covers(date time.Time) bool {
+	return !date.Before(r.From) && !date.After(r.To)
+}
+
+func (r *Reconciliation) line(id string) (StatementLine, bool) {
+	for _, line := range r.Lines {
+		if line.ID == id {
+			return line, true
+		}
+	}
+	return StatementLine{}, false
+}
+
+func (r *Reconciliation) matchIndex(lineID string) int {
+	for i, m := range r.Matches {
+		if m.LineID == lineID {
+			return i
+		}
+	}
+	return -1
+}
+
+// StatementMatcher pairs statement lines with transactions of the same signed
+// amount dated within Window of each other. MinSimilarity is the lowest
+// description similarity, from 0 to 1, accepted for a pair; a description
+// missing on either side is not held against it.
+type StatementMatcher struct {
+	Window        time.Duration
+	MinSimilarity float64
+}
+
+func DefaultStatementMatcher() StatementMatcher {
+	return StatementMatcher{
+		Window:        3 * 24 * time.Hour,
+		MinSimilarity: 0.2,
+	}
+}
+
+func (m StatementMatcher) candidate(line StatementLine, tx *Transaction) (float64, bool) {
+	if toCents(line.Amount) != toCents(signedAmount(tx)) {
+		return 0, false
+	}
+	if dateGap(line.Date, tx.Date) > m.Window {
+		return 0, false
+	}
+	if line.Description == "" || tx.Description == "" {
+		return 0, true
+	}
+	s := similarity(line.Description, tx.Description)
+	return s, s >= m.MinSimilarity
+}
+
+// match pairs lines with transactions, taking the most similar descriptions
+// first and then the closest dates. Each side is used at most once.
+func (m StatementMatcher) match(lines []StatementLine, txs []*Transaction) []Match {
+	type pair struct {
+		line, tx   int
+		similarity float64
+		gap        time.Duration
+	}
+	var pairs []pair
+	for i, line := range lines {
+		for j, tx := range txs {
+			if s, ok := m.candidate(line, tx); ok {
+				pairs = append(pairs, pair{i, j, s, dateGap(line.Date, tx.Date)})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if pairs[a].similarity != pairs[b].similarity {
+			return pairs[a].similarity > pairs[b].similarity
+		}
+		return pairs[a].gap < pairs[b].gap
+	})
+
+	usedLines := make(map[int]bool)
+	usedTxs := make(map[int]bool)
+	var matches []Match
+	for _, p := range pairs {
+		if usedLines[p.line] || usedTxs[p.tx] {
+			continue
+		}
+		usedLines[p.line], usedTxs[p.tx] = true, true
+		matches = append(matches, Match{LineID: lines[p.line].ID, TransactionID: txs[p.tx].ID, Similarity: p.similarity})
+	}
+	return matches
+}
+
+// signedAmount is the transaction as a bank would show it: money in is
+// positive, money out negative.
+func signedAmount(tx *Transaction) float64 {
+	if tx.Type == "income" {
+		return tx.Amount
+	}
+	return -tx.Amount
+}
+
+func dateGap(a, b time.Time) time.Duration {
+	if a.After(b) {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
+
+// similarity compares descriptions by the letter pairs they share (the Dice
+// coefficient), ignoring case, punctuation and spacing, so "AMZN Mktp US" still
+// resembles "Amazon marketplace".
+func similarity(a, b string) float64 {
+	x, y := bigrams(a), bigrams(b)
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+
+	var shared, total int
+	for gram, n := range x {
+		shared += min(n, y[gram])
+		total += n
+	}
+	for _, n := range y {
+		total += n
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func bigrams(s string) map[string]int {
+	var runes []rune
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// MatchedLine is a statement line with the transaction it was matched to.
+type MatchedLine struct {
+	Line        StatementLine
+	Transaction *Transaction
+	Similarity  float64
+}
+
+// ReconciliationView is a reconciliation with what is still unmatched on both
+// sides: statement lines without a transaction, and transactions in the
+// period that are neither matched nor already reconciled.
+type ReconciliationView struct {
+	ID                    string
+	From                  time.Time
+	To                    time.Time
+	CreatedAt             time.Time
+	CompletedAt           *time.Time
+	Matches               []MatchedLine
+	UnmatchedLines        []StatementLine
+	UnmatchedTransactions []*Transaction
+}
+
+func (l *Ledger) SetStatementMatcher(m StatementMatcher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.matcher = m
+}
+
+// StartReconciliation records a statement for the period from to to and
+// matches its lines against the ledger. Lines are numbered from 1 in
+// statement order; matched transactions are marked cleared.
+func (l *Ledger) StartReconciliation(from, to time.Time, lines []StatementLine) (*ReconciliationView, error) {
+	if to.Before(from) {
+		return nil, errors.New("statement period ends before it starts")
+	}
+	lines = slices.Clone(lines)
+	for i := range lines {
+		lines[i].ID = strconv.Itoa(i + 1)
+		if lines[i].Amount == 0 {
+			return nil, fmt.Errorf("statement line %d has no amount", i+1)
+		}
+		if lines[i].Date.Before(from) || lines[i].Date.After(to) {
+			return nil, fmt.Errorf("statement line %d is outside the statement period", i+1)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, r := range l.reconciliations {
+		if r.Completed() && !r.From.After(to) && !from.After(r.To) {
+			return nil, ErrPeriodReconciled
+		}
+	}
+
+	rec := &Reconciliation{
+		ID:        uuid.New().String(),
+		From:      from,
+		To:        to,
+		Lines:     lines,
+		CreatedAt: time.Now().UTC(),
+	}
+	rec.Matches = l.matcher.match(lines, l.reconcilable(rec))
+	for _, m := range rec.Matches {
+		l.setStatus(m.TransactionID, StatusCleared)
+	}
+	l.reconciliations[rec.ID] = rec
+	return l.reconciliationView(rec), nil
+}
+
+func (l *Ledger) GetReconciliation(id string) (*ReconciliationView, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	rec, exists := l.reconciliations[id]
+	if !exists {
+		return nil, ErrReconciliationNotFound
+	}
+	return l.reconciliationView(rec), nil
+}
+
+// ListReconciliations returns reconciliations ordered by statement period.
+func (l *Ledger) ListReconciliations() []*ReconciliationView {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	views := make([]*ReconciliationView, 0, len(l.reconciliations))
+	for _, rec := range l.sortedReconciliations() {
+		views = append(views, l.reconciliationView(rec))
+	}
+	return views
+}
+
+// MatchStatementLine pairs a line with a transaction by hand, whatever their
+// amounts and dates, and marks the transaction cleared.
+func (l *Ledger) MatchStatementLine(id, lineID, txID string) (*ReconciliationView, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec, err := l.openReconciliation(id)
+	if err != nil {
+		return nil, err
+	}
+	line, exists := rec.line(lineID)
+	if !exists {
+		return nil, ErrStatementLineNotFound
+	}
+	i := l.findTransaction(txID)
+	if i < 0 {
+		return nil, ErrTransactionNotFound
+	}
+	tx := l.Transactions[i]
+	if tx.Status == StatusPending {
+		return nil, fmt.Errorf("%w: %s cannot be matched before its date", ErrTransactionPending, txID)
+	}
+	if rec.matchIndex(lineID) >= 0 || l.claimedTransactions()[txID] || tx.Status == StatusReconciled {
+		return nil, ErrAlreadyMatched
+	}
+
+	rec.Matches = append(rec.Matches, Match{LineID: lineID, TransactionID: txID, Similarity: similarity(line.Description, tx.Description)})
+	l.setStatus(txID, StatusCleared)
+	return l.reconciliationView(rec), nil
+}
+
+// UnmatchStatementLine undoes the match of a line, marking its transaction
+// uncleared again.
+func (l *Ledger) UnmatchStatementLine(id, lineID string) (*ReconciliationView, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec, err := l.openReconciliation(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := rec.line(lineID); !exists {
+		return nil, ErrStatementLineNotFound
+	}
+	if i := rec.matchIndex(lineID); i >= 0 {
+		l.setStatus(rec.Matches[i].TransactionID, StatusUncleared)
+		rec.Matches = slices.Delete(slices.Clone(rec.Matches), i, i+1)
+	}
+	return l.reconciliationView(rec), nil
+}
+
+// CompleteReconciliation marks every matched transaction reconciled and
+// locks the statement period. All statement lines must be matched; ledger
+// transactions missing from the statement may stay unmatched.
+func (l *Ledger) CompleteReconciliation(id string) (*ReconciliationView, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec, err := l.openReconciliation(id)
+	if err != nil {
+		return nil, err
+	}
+	view := l.reconciliationView(rec)
+	if len(view.UnmatchedLines) > 0 {
+		return nil, fmt.Errorf("%w: %d left", ErrUnmatchedLines, len(view.UnmatchedLines))
+	}
+
+	matches := make([]Match, 0, len(view.Matches))
+	for _, m := range view.Matches {
+		l.setStatus(m.Transaction.ID, StatusReconciled)
+		matches = append(matches, Match{LineID: m.Line.ID, TransactionID: m.Transaction.ID, Similarity: m.Similarity})
+	}
+	now := time.Now().UTC()
+	rec.Matches = matches
+	rec.CompletedAt = &now
+	return l.reconciliationView(rec), nil
+}
+
+// SetTransactionStatus marks a transaction cleared or uncleared by hand.
+// Transactions only become reconciled by completing a reconciliation.
+func (l *Ledger) SetTransactionStatus(id, status string) (*Transaction, error) {
+	if status != StatusUncleared && status != StatusCleared {
+		return nil, errors.New("status must be 'uncleared' or 'cleared'")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := l.findTransaction(id)
+	if i < 0 {
+		return nil, ErrTransactionNotFound
+	}
+	if err := l.checkReconciled(l.Transactions[i]); err != nil {
+		return nil, err
+	}
+	if l.Transactions[i].Status == StatusPending {
+		return nil, fmt.Errorf("%w: %s cannot be cleared before its date", ErrTransactionPending, id)
+	}
+	l.setStatus(id, status)
+	return l.Transactions[l.findTransaction(id)], nil
+}
+
+// checkReconciled fails if old, when given, is reconciled or any of the dates
+// falls in a completed reconciliation's period.
+func (l *Ledger) checkReconciled(old *Transaction, dates ...time.Time) error {
+	if old != nil {
+		if old.Status == StatusReconciled {
+			return fmt.Errorf("%w: transaction %s is reconciled", ErrPeriodReconciled, old.ID)
+		}
+		dates = append(dates, old.Date)
+	}
+	for _, rec := range l.reconciliations {
+		if !rec.Completed() {
+			continue
+		}
+		for _, date := range dates {
+			if rec.covers(date) {
+				return fmt.Errorf("%w: %s to %s", ErrPeriodReconciled, rec.From.Format(dateLayout), rec.To.Format(dateLayout))
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Ledger) openReconciliation(id string) (*Reconciliation, error) {
+	rec, exists := l.reconciliations[id]
+	if !exists {
+		return nil, ErrReconciliationNotFound
+	}
+	if rec.Completed() {
+		return nil, ErrReconciliationCompleted
+	}
+	return rec, nil
+}
+
+func (l *Ledger) setStatus(id, status string) {
+	i := l.findTransaction(id)
+	if i < 0 || l.Transactions[i].Status == status {
+		return
+	}
+	updated := *l.Transactions[i]
+	updated.Status = status
+	l.replaceTransaction(i, &updated)
+}
+
+// claimedTransactions are those matched by an open reconciliation.
+func (l *Ledger) claimedTransactions() map[string]bool {
+	claimed := make(map[string]bool)
+	for _, rec := range l.reconciliations {
+		if rec.Completed() {
+			continue
+		}
+		for _, m := range rec.Matches {
+			claimed[m.TransactionID] = true
+		}
+	}
+	return claimed
+}
+
+// reconcilable lists the transactions a new statement may match: those dated
+// within the matcher's window of the period that are not pending, already
+// reconciled or matched elsewhere.
+func (l *Ledger) reconcilable(rec *Reconciliation) []*Transaction {
+	from, to := rec.From.Add(-l.matcher.Window), rec.To.Add(l.matcher.Window)
+	claimed := l.claimedTransactions()
+
+	var txs []*Transaction
+	for _, tx := range l.Transactions {
+		if tx.Date.Before(from) || tx.Date.After(to) || tx.Status == StatusReconciled || tx.Status == StatusPending || claimed[tx.ID] {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// reconciliationView drops matches whose transaction has since been deleted,
+// leaving their lines unmatched.
+func (l *Ledger) reconciliationView(rec *Reconciliation) *ReconciliationView {
+	view := &ReconciliationView{
+		ID:                    rec.ID,
+		From:                  rec.From,
+		To:                    rec.To,
+		CreatedAt:             rec.CreatedAt,
+		CompletedAt:           rec.CompletedAt,
+		Matches:               make([]MatchedLine, 0, len(rec.Matches)),
+		UnmatchedLines:        make([]StatementLine, 0),
+		UnmatchedTransactions: make([]*Transaction, 0),
+	}
+
+	matched := make(map[string]bool, len(rec.Matches))
+	for _, line := range rec.Lines {
+		i := rec.matchIndex(line.ID)
+		if i < 0 {
+			view.UnmatchedLines = append(view.UnmatchedLines, line)
+			continue
+		}
+		m := rec.Matches[i]
+		j := l.findTransaction(m.TransactionID)
+		if j < 0 {
+			view.UnmatchedLines = append(view.UnmatchedLines, line)
+			continue
+		}
+		matched[m.TransactionID] = true
+		view.Matches = append(view.Matches, MatchedLine{Line: line, Transaction: l.Transactions[j], Similarity: m.Similarity})
+	}
+
+	if !rec.Completed() {
+		for _, tx := range l.Transactions {
+			if rec.covers(tx.Date) && !matched[tx.ID] && tx.Status != StatusReconciled {
+				view.UnmatchedTransactions = append(view.UnmatchedTransactions, tx)
+			}
+		}
+	}
+	return view
+}
+
+func (l *Ledger) sortedReconciliations() []*Reconciliation {
+	recs := make([]*Reconciliation, 0, len(l.reconciliations))
+	for _, rec := range l.reconciliations {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if !recs[i].From.Equal(recs[j].From) {
+			return recs[i].From.Before(recs[j].From)
+		}
+		return recs[i].ID < recs[j].ID
+	})
+	return recs
+}
+
+type StatementLineRequest struct {
+	Date        string  `json:"date" format:"date"`
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+}
+
+type CreateReconciliationRequest struct {
+	From  string                 `json:"from" format:"date"`
+	To    string                 `json:"to" format:"date"`
+	Lines []StatementLineRequest `json:"lines"`
+}
+
+type MatchRequest struct {
+	LineID        string `json:"line_id"`
+	TransactionID string `json:"transaction_id"`
+}
+
+type TransactionStatusRequest struct {
+	Status string `json:"status" enum:"uncleared,cleared"`
+}
+
+type StatementLineResponse struct {
+	ID          string  `json:"id"`
+	Date        string  `json:"date" format:"date"`
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+}
+
+type MatchResponse struct {
+	Line        StatementLineResponse `json:"line"`
+	Transaction TransactionResponse   `json:"transaction"`
+	Similarity  float64               `json:"similarity"`
+}
+
+type ReconciliationResponse struct {
+	ID                    string                  `json:"id"`
+	From                  string                  `json:"from" format:"date"`
+	To                    string                  `json:"to" format:"date"`
+	Status                string                  `json:"status" enum:"open,completed"`
+	CreatedAt             time.Time               `json:"created_at"`
+	CompletedAt           *time.Time              `json:"completed_at,omitempty"`
+	Matches               []MatchResponse         `json:"matches"`
+	UnmatchedLines        []StatementLineResponse `json:"unmatched_lines"`
+	UnmatchedTransactions []TransactionResponse   `json:"unmatched_transactions"`
+}
+
+func newStatementLineResponse(line StatementLine) StatementLineResponse {
+	return StatementLineResponse{
+		ID:          line.ID,
+		Date:        line.Date.Format(dateLayout),
+		Amount:      line.Amount,
+		Description: line.Description,
+	}
+}
+
+func newReconciliationResponse(view *ReconciliationView) ReconciliationResponse {
+	response := ReconciliationResponse{
+		ID:                    view.ID,
+		From:                  view.From.Format(dateLayout),
+		To:                    view.To.Format(dateLayout),
+		Status:                "open",
+		CreatedAt:             view.CreatedAt,
+		CompletedAt:           view.CompletedAt,
+		Matches:               make([]MatchResponse, len(view.Matches)),
+		UnmatchedLines:        make([]StatementLineResponse, len(view.UnmatchedLines)),
+		UnmatchedTransactions: make([]TransactionResponse, len(view.UnmatchedTransactions)),
+	}
+	if view.CompletedAt != nil {
+		response.Status = "completed"
+	}
+	for i, m := range view.Matches {
+		response.Matches[i] = MatchResponse{
+			Line:        newStatementLineResponse(m.Line),
+			Transaction: newTransactionResponse(m.Transaction),
+			Similarity:  math.Round(m.Similarity*100) / 100,
+		}
+	}
+	for i, line := range view.UnmatchedLines {
+		response.UnmatchedLines[i] = newStatementLineResponse(line)
+	}
+	for i, tx := range view.UnmatchedTransactions {
+		response.UnmatchedTransactions[i] = newTransactionResponse(tx)
+	}
+	return response
+}
+
+func writeReconciliationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrReconciliationNotFound), errors.Is(err, ErrStatementLineNotFound), errors.Is(err, ErrTransactionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrPeriodReconciled):
+		writeErrorCode(w, http.StatusConflict, ErrCodePeriodLocked, err.Error())
+	case errors.Is(err, ErrReconciliationCompleted), errors.Is(err, ErrAlreadyMatched), errors.Is(err, ErrUnmatchedLines), errors.Is(err, ErrTransactionPending):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, err.Error())
+	}
+}
+
+func (h *Handler) CreateReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateReconciliationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	from, fromErr := time.Parse(dateLayout, req.From)
+	to, toErr := time.Parse(dateLayout, req.To)
+	if fromErr != nil || toErr != nil {
+		writeError(w, http.StatusBadRequest, "invalid date format, use YYYY-MM-DD")
+		return
+	}
+	lines := make([]StatementLine, len(req.Lines))
+	for i, line := range req.Lines {
+		date, err := time.Parse(dateLayout, line.Date)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date format, use YYYY-MM-DD")
+			return
+		}
+		lines[i] = StatementLine{Date: date, Amount: line.Amount, Description: line.Description}
+	}
+
+	view, err := h.ledger.StartReconciliation(from, to, lines)
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newReconciliationResponse(view))
+}
+
+func (h *Handler) ListReconciliationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	views := h.ledger.ListReconciliations()
+	response := make([]ReconciliationResponse, len(views))
+	for i, view := range views {
+		response[i] = newReconciliationResponse(view)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) GetReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	view, err := h.ledger.GetReconciliation(r.PathValue("id"))
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReconciliationResponse(view))
+}
+
+func (h *Handler) MatchStatementLineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req MatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	view, err := h.ledger.MatchStatementLine(r.PathValue("id"), req.LineID, req.TransactionID)
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReconciliationResponse(view))
+}
+
+func (h *Handler) UnmatchStatementLineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	view, err := h.ledger.UnmatchStatementLine(r.PathValue("id"), r.PathValue("line"))
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReconciliationResponse(view))
+}
+
+func (h *Handler) CompleteReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	view, err := h.ledger.CompleteReconciliation(r.PathValue("id"))
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newReconciliationResponse(view))
+}
+
+func (h *Handler) SetTransactionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req TransactionStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	tx, err := h.ledger.SetTransactionStatus(r.PathValue("id"), req.Status)
+	if err != nil {
+		writeReconciliationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newTransactionResponse(tx))
+}
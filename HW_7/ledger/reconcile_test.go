@@ -0,0 +1,235 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"Coffee House", "coffee house", 1, 1},
+		{"AMZN Mktp US", "Amazon marketplace", 0.2, 0.8},
+		{"Shell fuel #2231", "Shell petrol station", 0.2, 0.8},
+		{"Rent March", "Groceries", 0, 0.1},
+		{"", "Groceries", 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("similarity(%q, %q) = %.2f, expected between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func seedReconcileLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	ledger := NewLedger()
+	txs := []*Transaction{
+		{ID: "salary", Amount: 3000, Category: "salary", Description: "Acme payroll", Date: date("2024-05-01"), Type: "income"},
+		{ID: "coffee-1", Amount: 4.5, Category: "food", Description: "Coffee House", Date: date("2024-05-03"), Type: "expense"},
+		{ID: "coffee-2", Amount: 4.5, Category: "food", Description: "Coffee House", Date: date("2024-05-09"), Type: "expense"},
+		{ID: "amazon", Amount: 59.99, Category: "shopping", Description: "Amazon marketplace", Date: date("2024-05-12"), Type: "expense"},
+		{ID: "cash", Amount: 20, Category: "food", Description: "Farmers market", Date: date("2024-05-18"), Type: "expense"},
+	}
+	mustAdd(t, ledger, txs...)
+	return ledger
+}
+
+func statement() []StatementLine {
+	return []StatementLine{
+		{Date: date("2024-05-02"), Amount: 3000, Description: "ACME CORP PAYROLL"},
+		{Date: date("2024-05-10"), Amount: -4.5, Description: "COFFEE HOUSE 0231"},
+		{Date: date("2024-05-04"), Amount: -4.5, Description: "COFFEE HOUSE 0231"},
+		{Date: date("2024-05-13"), Amount: -59.99, Description: "AMZN Mktp US"},
+		{Date: date("2024-05-20"), Amount: -12.0, Description: "Bank fee"},
+	}
+}
+
+func txStatus(t *testing.T, l *Ledger, id string) string {
+	t.Helper()
+	for _, tx := range l.ListTransactions() {
+		if tx.ID == id {
+			return tx.Status
+		}
+	}
+	t.Fatalf("Transaction %s not found", id)
+	return ""
+}
+
+func TestLedger_Reconciliation(t *testing.T) {
+	ledger := seedReconcileLedger(t)
+
+	view, err := ledger.StartReconciliation(date("2024-05-01"), date("2024-05-31"), statement())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{"1": "salary", "2": "coffee-2", "3": "coffee-1", "4": "amazon"}
+	if len(view.Matches) != len(want) {
+		t.Fatalf("Expected %d matches, got %+v", len(want), view.Matches)
+	}
+	for _, m := range view.Matches {
+		if want[m.Line.ID] != m.Transaction.ID {
+			t.Errorf("Expected line %s to match %s, got %s", m.Line.ID, want[m.Line.ID], m.Transaction.ID)
+		}
+		if m.Transaction.Status != StatusCleared {
+			t.Errorf("Expected %s to be cleared, got %q", m.Transaction.ID, m.Transaction.Status)
+		}
+	}
+	if len(view.UnmatchedLines) != 1 || view.UnmatchedLines[0].Description != "Bank fee" {
+		t.Errorf("Expected bank fee unmatched, got %+v", view.UnmatchedLines)
+	}
+	if len(view.UnmatchedTransactions) != 1 || view.UnmatchedTransactions[0].ID != "cash" {
+		t.Errorf("Expected cash unmatched, got %+v", view.UnmatchedTransactions)
+	}
+
+	if _, err := ledger.CompleteReconciliation(view.ID); !errors.Is(err, ErrUnmatchedLines) {
+		t.Fatalf("Expected %v, got %v", ErrUnmatchedLines, err)
+	}
+
+	fee := &Transaction{ID: "fee", Amount: 12, Category: "fees", Date: date("2024-05-20"), Type: "expense"}
+	if err := ledger.AddTransaction(fee); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := ledger.MatchStatementLine(view.ID, "5", "fee"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := ledger.MatchStatementLine(view.ID, "5", "cash"); !errors.Is(err, ErrAlreadyMatched) {
+		t.Errorf("Expected %v, got %v", ErrAlreadyMatched, err)
+	}
+
+	view, err = ledger.CompleteReconciliation(view.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if view.CompletedAt == nil || txStatus(t, ledger, "fee") != StatusReconciled || txStatus(t, ledger, "cash") != StatusUncleared {
+		t.Errorf("Unexpected completed reconciliation %+v", view)
+	}
+
+	t.Run("period is locked", func(t *testing.T) {
+		late := &Transaction{ID: "late", Amount: 5, Category: "food", Date: date("2024-05-15"), Type: "expense"}
+		moved := &Transaction{ID: "cash", Amount: 20, Category: "food", Date: date("2024-06-02"), Type: "expense"}
+		checks := []struct {
+			name string
+			err  error
+		}{
+			{"add", ledger.AddTransaction(late)},
+			{"update", ledger.UpdateTransaction(moved)},
+			{"delete", ledger.DeleteTransaction("salary")},
+			{"overlapping statement", func() error {
+				_, err := ledger.StartReconciliation(date("2024-05-25"), date("2024-06-30"), nil)
+				return err
+			}()},
+			{"status", func() error {
+				_, err := ledger.SetTransactionStatus("cash", StatusCleared)
+				return err
+			}()},
+		}
+		for _, c := range checks {
+			if !errors.Is(c.err, ErrPeriodReconciled) {
+				t.Errorf("%s: expected %v, got %v", c.name, ErrPeriodReconciled, c.err)
+			}
+		}
+
+		if err := ledger.AddTransaction(&Transaction{ID: "june", Amount: 5, Category: "food", Date: date("2024-06-01"), Type: "expense"}); err != nil {
+			t.Errorf("Expected next period to stay open, got %v", err)
+		}
+	})
+
+	t.Run("survives snapshot", func(t *testing.T) {
+		var buf strings.Builder
+		if err := ledger.WriteSnapshot(&buf); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		restored := NewLedger()
+		if err := restored.ReadSnapshot(strings.NewReader(buf.String())); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := restored.DeleteTransaction("amazon"); !errors.Is(err, ErrPeriodReconciled) {
+			t.Errorf("Expected %v, got %v", ErrPeriodReconciled, err)
+		}
+	})
+}
+
+func TestReconciliationHandlers(t *testing.T) {
+	ledger := seedReconcileLedger(t)
+	handler := NewHandler(ledger)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/reconciliations", handler.CreateReconciliationHandler)
+	mux.HandleFunc("GET /api/reconciliations/{id}", handler.GetReconciliationHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/matches", handler.MatchStatementLineHandler)
+	mux.HandleFunc("DELETE /api/reconciliations/{id}/matches/{line}", handler.UnmatchStatementLineHandler)
+	mux.HandleFunc("POST /api/reconciliations/{id}/complete", handler.CompleteReconciliationHandler)
+	mux.HandleFunc("PUT /api/transactions/{id}/status", handler.SetTransactionStatusHandler)
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, ReconciliationResponse) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var response ReconciliationResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response
+	}
+
+	body := `{"from":"2024-05-01","to":"2024-05-31","lines":[
+		{"date":"2024-05-02","amount":3000,"description":"ACME CORP PAYROLL"},
+		{"date":"2024-05-19","amount":-20,"description":"POS 88123"}]}`
+	rr, rec := do("POST", "/api/reconciliations", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	if rec.Status != "open" || len(rec.Matches) != 1 || rec.Matches[0].Transaction.Status != StatusCleared {
+		t.Errorf("Unexpected reconciliation %+v", rec)
+	}
+	if len(rec.UnmatchedLines) != 1 || len(rec.UnmatchedTransactions) != 4 {
+		t.Errorf("Expected 1 unmatched line and 4 transactions, got %d and %d", len(rec.UnmatchedLines), len(rec.UnmatchedTransactions))
+	}
+	base := "/api/reconciliations/" + rec.ID
+
+	if rr, _ := do("POST", base+"/complete", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	rr, rec = do("POST", base+"/matches", `{"line_id":"2","transaction_id":"cash"}`)
+	if rr.Code != http.StatusOK || len(rec.UnmatchedLines) != 0 {
+		t.Fatalf("Expected line matched, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr, rec = do("DELETE", base+"/matches/1", "")
+	if rr.Code != http.StatusOK || len(rec.UnmatchedLines) != 1 || txStatus(t, ledger, "salary") != StatusUncleared {
+		t.Errorf("Expected line unmatched, got %d: %s", rr.Code, rr.Body.String())
+	}
+	do("POST", base+"/matches", `{"line_id":"1","transaction_id":"salary"}`)
+
+	rr, rec = do("POST", base+"/complete", "")
+	if rr.Code != http.StatusOK || rec.Status != "completed" || rec.CompletedAt == nil {
+		t.Fatalf("Expected completed reconciliation, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"unknown reconciliation", "GET", "/api/reconciliations/missing", "", http.StatusNotFound},
+		{"match after completion", "POST", base + "/matches", `{"line_id":"1","transaction_id":"amazon"}`, http.StatusConflict},
+		{"backdated transaction status", "PUT", "/api/transactions/amazon/status", `{"status":"cleared"}`, http.StatusConflict},
+		{"invalid status", "PUT", "/api/transactions/amazon/status", `{"status":"reconciled"}`, http.StatusBadRequest},
+		{"invalid period", "POST", "/api/reconciliations", `{"from":"2024-06-30","to":"2024-06-01","lines":[]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rr, _ := do(tt.method, tt.path, tt.body); rr.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Summary reports the month containing AsOf: income and expenses posted so
+// far, every budget with its spending, and the state of every goal.
+type Summary struct {
+	PeriodStart time.Time
+	AsOf        time.Time
+	Income      float64
+	Expenses    float64
+	Budgets     []Budget
+	Goals       []*GoalProgress
+}
+
+func (l *Ledger) Summary(asOf time.Time) *Summary {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	s := &Summary{
+		PeriodStart: time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, asOf.Location()),
+		AsOf:        asOf,
+		Budgets:     make([]Budget, 0, len(l.Budgets)),
+		Goals:       make([]*GoalProgress, 0, len(l.Goals)),
+	}
+
+	for _, tx := range l.Transactions {
+		if periodsBefore(s.PeriodStart, tx.Date) != 0 || tx.Date.After(asOf) || tx.Status == StatusPending {
+			continue
+		}
+		switch tx.Type {
+		case "income":
+			s.Income += tx.Amount
+		case "expense":
+			s.Expenses += tx.Amount
+		}
+	}
+
+	for _, budget := range l.Budgets {
+		s.Budgets = append(s.Budgets, Budget{
+			Category:  budget.Category,
+			Limit:     budget.Limit,
+			Spent:     l.spending.total(budget.Category),
+			Committed: l.spending.committed(budget.Category),
+		})
+	}
+	sort.Slice(s.Budgets, func(i, j int) bool {
+		return s.Budgets[i].Category < s.Budgets[j].Category
+	})
+
+	for _, goal := range l.sortedGoals() {
+		s.Goals = append(s.Goals, l.goalProgress(goal, asOf))
+	}
+	return s
+}
+
+type SummaryResponse struct {
+	Period   string           `json:"period"`
+	AsOf     string           `json:"as_of" format:"date"`
+	Income   float64          `json:"income"`
+	Expenses float64          `json:"expenses"`
+	Net      float64          `json:"net"`
+	Budgets  []BudgetResponse `json:"budgets"`
+	Goals    []GoalResponse   `json:"goals"`
+}
+
+type SummaryQuery struct {
+	AsOf time.Time `query:"as_of" format:"date"`
+}
+
+func (h *Handler) SummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s := h.ledger.Summary(asOf)
+	response := SummaryResponse{
+		Period:   periodKey(s.PeriodStart),
+		AsOf:     s.AsOf.Format(dateLayout),
+		Income:   s.Income,
+		Expenses: s.Expenses,
+		Net:      s.Income - s.Expenses,
+		Budgets:  make([]BudgetResponse, len(s.Budgets)),
+		Goals:    make([]GoalResponse, len(s.Goals)),
+	}
+	for i, budget := range s.Budgets {
+		response.Budgets[i] = newBudgetResponse(budget)
+	}
+	for i, p := range s.Goals {
+		response.Goals[i] = newGoalResponse(p)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -1,6 +1,18 @@
 package ledger
 
 func (l *Ledger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, tx := range l.Transactions {
+		l.detector.Forget(tx)
+	}
 	l.Transactions = make([]*Transaction, 0)
 	l.Budgets = make(map[string]*Budget)
+	l.Goals = make(map[string]*Goal)
+	l.spending = newSpendingIndex()
+	l.tags = make(tagIndex)
+	l.reconciliations = make(map[string]*Reconciliation)
+	l.closedPeriods = make(map[string]*ClosedPeriod)
+	l.periodAudit = nil
 }
@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+var ErrTransactionPending = errors.New("transaction is pending")
+
+// StatusPending marks a transaction dated in the future. Until it posts on its
+// date it counts as committed rather than spent, and it stays out of reports
+// of actual spending.
+const StatusPending = "pending"
+
+// fileStatus sets the status of a new or updated transaction: pending while it
+// is dated after now, otherwise its own status, then previous, then
+// uncleared. A pending status is not carried over once the date has passed.
+func (t *Transaction) fileStatus(previous string, now time.Time) {
+	switch {
+	case t.Date.After(now):
+		t.Status = StatusPending
+	case t.Status != "":
+	case previous != "" && previous != StatusPending:
+		t.Status = previous
+	default:
+		t.Status = StatusUncleared
+	}
+}
+
+// PostDue posts the pending transactions dated at or before now, moving them
+// from committed to spent, and returns them. Those dated in a closed or
+// reconciled period stay pending until it is reopened and are returned as
+// held.
+func (l *Ledger) PostDue(now time.Time) (posted, held []*Transaction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, tx := range l.Transactions {
+		if tx.Status != StatusPending || tx.Date.After(now) {
+			continue
+		}
+		if err := l.checkEditable(tx); err != nil {
+			held = append(held, tx)
+			continue
+		}
+		updated := *tx
+		updated.Status = StatusUncleared
+		l.spending.remove(tx)
+		l.replaceTransaction(i, &updated)
+		l.spending.add(&updated)
+		l.notifyBudgets(&updated)
+		posted = append(posted, &updated)
+	}
+	return posted, held
+}
+
+// PostScheduled runs PostDue every interval until ctx is done, warning once
+// about each transaction held by a locked period.
+func (l *Ledger) PostScheduled(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	warned := make(map[string]bool)
+	for {
+		posted, held := l.PostDue(time.Now())
+		if len(posted) > 0 {
+			slog.Info("posted scheduled transactions", "count", len(posted))
+		}
+		for _, tx := range held {
+			if !warned[tx.ID] {
+				warned[tx.ID] = true
+				slog.Warn("scheduled transaction held by locked period", "id", tx.ID, "date", tx.Date.Format(dateLayout))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *Ledger) GetCategoryCommitted(category string) float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.spending.committed(category)
+}
@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func schedulingLedger() *Ledger {
+	ledger := NewLedger()
+	policy := DefaultValidationPolicy()
+	policy.Transaction = policy.Transaction.With(DateWindow(0, 60*24*time.Hour))
+	ledger.SetValidationPolicy(policy)
+	return ledger
+}
+
+func TestLedger_PendingTransactions(t *testing.T) {
+	ledger := schedulingLedger()
+	ledger.SetBudget(&Budget{Category: "utilities", Limit: 300})
+
+	now := time.Now()
+	paid := &Transaction{ID: "water", Amount: 40, Category: "utilities", Date: now.AddDate(0, 0, -1), Type: "expense"}
+	bill := &Transaction{ID: "power", Amount: 120, Category: "utilities", Description: "Power bill", Date: now.AddDate(0, 0, 7), Type: "expense", Status: StatusCleared}
+	mustAdd(t, ledger, paid, bill)
+
+	if bill.Status != StatusPending || paid.Status != StatusUncleared {
+		t.Errorf("Expected pending and uncleared, got %q and %q", bill.Status, paid.Status)
+	}
+	if spent, committed := ledger.GetCategorySpending("utilities"), ledger.GetCategoryCommitted("utilities"); spent != 40 || committed != 120 {
+		t.Errorf("Expected 40 spent and 120 committed, got %.2f and %.2f", spent, committed)
+	}
+	err := ledger.AddTransaction(&Transaction{ID: "gas", Amount: 150, Category: "utilities", Date: now, Type: "expense"})
+	var exceeded *BudgetExceededError
+	if !errors.As(err, &exceeded) || exceeded.Used != 160 || exceeded.Limit != 300 {
+		t.Errorf("Expected committed spending to count against the budget, got %v", err)
+	}
+	if _, err := ledger.SetTransactionStatus("power", StatusCleared); !errors.Is(err, ErrTransactionPending) {
+		t.Errorf("Expected %v, got %v", ErrTransactionPending, err)
+	}
+
+	edited := *bill
+	edited.Description = "Power bill (estimate)"
+	if err := ledger.UpdateTransaction(&edited); err != nil || edited.Status != StatusPending {
+		t.Fatalf("Expected an update keeping the pending status to succeed, got %v and %q", err, edited.Status)
+	}
+	bill = &edited
+
+	f, err := ledger.ForecastBudget("utilities", bill.Date)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(f.Scheduled) != 1 || f.Scheduled[0].Amount != 120 || f.Projected < f.Spent+120 {
+		t.Errorf("Expected the bill to be forecast, got %+v", f)
+	}
+
+	if posted, _ := ledger.PostDue(now); len(posted) != 0 {
+		t.Errorf("Expected nothing due yet, got %d", len(posted))
+	}
+	posted, _ := ledger.PostDue(now.AddDate(0, 0, 8))
+	if len(posted) != 1 || posted[0].ID != "power" || posted[0].Status != StatusUncleared {
+		t.Fatalf("Expected the bill to post, got %+v", posted)
+	}
+	if bill.Status != StatusPending {
+		t.Error("Expected posting to leave the stored transaction untouched")
+	}
+	if spent, committed := ledger.GetCategorySpending("utilities"), ledger.GetCategoryCommitted("utilities"); spent != 160 || committed != 0 {
+		t.Errorf("Expected 160 spent and nothing committed, got %.2f and %.2f", spent, committed)
+	}
+	if err := ledger.CheckConsistency(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLedger_PostDueInClosedPeriod(t *testing.T) {
+	ledger := schedulingLedger()
+	now := time.Now()
+	monthEnd := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location()).Add(-time.Second)
+	if err := ledger.AddTransaction(&Transaction{ID: "rent", Amount: 900, Category: "housing", Date: monthEnd, Type: "expense"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := ledger.ClosePeriod(periodKey(now), "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	posted, held := ledger.PostDue(monthEnd)
+	if len(posted) != 0 || len(held) != 1 || held[0].ID != "rent" {
+		t.Fatalf("Expected rent to be held, got %d posted and %d held", len(posted), len(held))
+	}
+	if spent := ledger.GetCategorySpending("housing"); spent != 0 || txStatus(t, ledger, "rent") != StatusPending {
+		t.Errorf("Expected the closed period to stay unchanged, got %.2f spent", spent)
+	}
+
+	if err := ledger.ReopenPeriod(periodKey(now), "alice", "post rent"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if posted, held := ledger.PostDue(monthEnd); len(posted) != 1 || len(held) != 0 {
+		t.Errorf("Expected rent to post once reopened, got %d posted and %d held", len(posted), len(held))
+	}
+	if err := ledger.CheckConsistency(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLedger_UpdatePendingTransaction(t *testing.T) {
+	ledger := schedulingLedger()
+	if err := ledger.AddTransaction(&Transaction{ID: "rent", Amount: 900, Category: "housing", Date: time.Now().AddDate(0, 0, 3), Type: "expense"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := ledger.UpdateTransaction(&Transaction{ID: "rent", Amount: 900, Category: "housing", Date: time.Now(), Type: "expense"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status := txStatus(t, ledger, "rent"); status != StatusUncleared {
+		t.Errorf("Expected a backdated pending transaction to post, got %q", status)
+	}
+	if err := ledger.CheckConsistency(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBudgetResponse_Committed(t *testing.T) {
+	ledger := schedulingLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 500})
+	ledger.AddTransaction(&Transaction{ID: "lunch", Amount: 20, Category: "food", Date: time.Now(), Type: "expense"})
+	ledger.AddTransaction(&Transaction{ID: "catering", Amount: 200, Category: "food", Date: time.Now().AddDate(0, 0, 5), Type: "expense"})
+	handler := NewHandler(ledger)
+
+	rr := httptest.NewRecorder()
+	handler.ListBudgetsHandler(rr, httptest.NewRequest("GET", "/api/budgets", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var budgets []BudgetResponse
+	json.Unmarshal(rr.Body.Bytes(), &budgets)
+	want := BudgetResponse{Category: "food", Limit: 500, Spent: 20, Committed: 200, Available: 280}
+	if len(budgets) != 1 || budgets[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, budgets)
+	}
+}
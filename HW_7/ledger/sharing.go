@@ -0,0 +1,376 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ShareEqual    = "equal"
+	ShareByShares = "shares"
+	ShareExact    = "exact"
+)
+
+// settlementCategory files the transfers recorded by settling up.
+const settlementCategory = "settlement"
+
+type Participant struct {
+	Member string `json:"member"`
+	// Shares weighs the participant under ShareByShares.
+	Shares float64 `json:"shares,omitempty"`
+	// Amount is the participant's part under ShareExact.
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// Sharing records that PaidBy paid a transaction on behalf of the
+// participants, who owe their part of it back.
+type Sharing struct {
+	PaidBy       string        `json:"paid_by"`
+	Method       string        `json:"method"`
+	Participants []Participant `json:"participants"`
+}
+
+func (s *Sharing) validate(total float64) error {
+	if s.PaidBy == "" {
+		return errors.New("sharing payer cannot be empty")
+	}
+	if len(s.Participants) == 0 {
+		return errors.New("sharing needs at least one participant")
+	}
+
+	seen := make(map[string]bool, len(s.Participants))
+	var sum float64
+	for _, p := range s.Participants {
+		if p.Member == "" {
+			return errors.New("participant member cannot be empty")
+		}
+		if seen[p.Member] {
+			return fmt.Errorf("duplicate participant %q", p.Member)
+		}
+		seen[p.Member] = true
+
+		switch s.Method {
+		case ShareEqual:
+		case ShareByShares:
+			if p.Shares <= 0 {
+				return errors.New("participant shares must be positive")
+			}
+		case ShareExact:
+			if p.Amount < 0 {
+				return errors.New("participant amount cannot be negative")
+			}
+			sum += p.Amount
+		default:
+			return errors.New("sharing method must be 'equal', 'shares' or 'exact'")
+		}
+	}
+
+	if s.Method == ShareExact && math.Abs(sum-total) > splitTolerance {
+		return fmt.Errorf("participant amounts sum to %.2f but amount is %.2f", sum, total)
+	}
+	return nil
+}
+
+// owedCents splits total among the participants in cents, in participant
+// order. Cents left over by rounding go to the largest remainders first.
+func (s *Sharing) owedCents(total float64) []int64 {
+	totalCents := toCents(total)
+	owed := make([]int64, len(s.Participants))
+
+	if s.Method == ShareExact {
+		for i, p := range s.Participants {
+			owed[i] = toCents(p.Amount)
+		}
+		return owed
+	}
+
+	weights := make([]float64, len(s.Participants))
+	var totalWeight float64
+	for i, p := range s.Participants {
+		weights[i] = 1
+		if s.Method == ShareByShares {
+			weights[i] = p.Shares
+		}
+		totalWeight += weights[i]
+	}
+
+	remainders := make([]float64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		exact := float64(totalCents) * weight / totalWeight
+		owed[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(owed[i])
+		allocated += owed[i]
+	}
+
+	order := make([]int, len(owed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; allocated < totalCents; i++ {
+		owed[order[i%len(order)]]++
+		allocated++
+	}
+	return owed
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+type MemberBalance struct {
+	Member string
+	Paid   float64
+	Owed   float64
+}
+
+// Net is positive when the member is owed money.
+func (b MemberBalance) Net() float64 {
+	return b.Paid - b.Owed
+}
+
+type Settlement struct {
+	From   string
+	To     string
+	Amount float64
+}
+
+// Balances sums, per member, what they paid for posted shared transactions
+// and what they owe, ordered by member.
+func (l *Ledger) Balances() []MemberBalance {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	paid := make(map[string]int64)
+	owed := make(map[string]int64)
+	for _, tx := range l.Transactions {
+		if tx.Sharing == nil || tx.Status == StatusPending {
+			continue
+		}
+		paid[tx.Sharing.PaidBy] += toCents(tx.Amount)
+		for i, cents := range tx.Sharing.owedCents(tx.Amount) {
+			owed[tx.Sharing.Participants[i].Member] += cents
+		}
+	}
+
+	members := make([]string, 0, len(paid)+len(owed))
+	for member := range paid {
+		members = append(members, member)
+	}
+	for member := range owed {
+		if _, exists := paid[member]; !exists {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+
+	balances := make([]MemberBalance, len(members))
+	for i, member := range members {
+		balances[i] = MemberBalance{Member: member, Paid: fromCents(paid[member]), Owed: fromCents(owed[member])}
+	}
+	return balances
+}
+
+// SettleUp plans transfers that clear every balance, repeatedly paying the
+// largest creditor from the largest debtor. This needs at most one transfer
+// fewer than there are members with a balance.
+func SettleUp(balances []MemberBalance) []Settlement {
+	type position struct {
+		member string
+		cents  int64
+	}
+	var creditors, debtors []position
+	for _, b := range balances {
+		switch net := toCents(b.Paid) - toCents(b.Owed); {
+		case net > 0:
+			creditors = append(creditors, position{b.Member, net})
+		case net < 0:
+			debtors = append(debtors, position{b.Member, -net})
+		}
+	}
+
+	largestFirst := func(positions []position) {
+		sort.SliceStable(positions, func(i, j int) bool { return positions[i].cents > positions[j].cents })
+	}
+
+	settlements := make([]Settlement, 0)
+	for len(creditors) > 0 && len(debtors) > 0 {
+		largestFirst(creditors)
+		largestFirst(debtors)
+
+		amount := min(creditors[0].cents, debtors[0].cents)
+		settlements = append(settlements, Settlement{From: debtors[0].member, To: creditors[0].member, Amount: fromCents(amount)})
+
+		creditors[0].cents -= amount
+		debtors[0].cents -= amount
+		if creditors[0].cents == 0 {
+			creditors = creditors[1:]
+		}
+		if debtors[0].cents == 0 {
+			debtors = debtors[1:]
+		}
+	}
+	return settlements
+}
+
+// NewSettlement builds the transfer recording that from paid to back: from
+// pays the whole amount on behalf of to, which cancels that much debt.
+func NewSettlement(id, from, to string, amount float64, date time.Time) *Transaction {
+	return &Transaction{
+		ID:          id,
+		Amount:      amount,
+		Category:    settlementCategory,
+		Description: fmt.Sprintf("%s settles up with %s", from, to),
+		Date:        date,
+		Type:        "transfer",
+		Sharing: &Sharing{
+			PaidBy:       from,
+			Method:       ShareExact,
+			Participants: []Participant{{Member: to, Amount: amount}},
+		},
+	}
+}
+
+type SharingRequest struct {
+	PaidBy       string               `json:"paid_by"`
+	Method       string               `json:"method" enum:"equal,shares,exact"`
+	Participants []ParticipantRequest `json:"participants"`
+}
+
+type ParticipantRequest struct {
+	Member string  `json:"member"`
+	Shares float64 `json:"shares,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+}
+
+type SharingResponse struct {
+	PaidBy       string                `json:"paid_by"`
+	Method       string                `json:"method" enum:"equal,shares,exact"`
+	Participants []ParticipantResponse `json:"participants"`
+}
+
+type ParticipantResponse struct {
+	Member string  `json:"member"`
+	Shares float64 `json:"shares,omitempty"`
+	Owed   float64 `json:"owed"`
+}
+
+func (req *SharingRequest) sharing() *Sharing {
+	if req == nil {
+		return nil
+	}
+	s := &Sharing{PaidBy: req.PaidBy, Method: req.Method}
+	for _, p := range req.Participants {
+		s.Participants = append(s.Participants, Participant{Member: p.Member, Shares: p.Shares, Amount: p.Amount})
+	}
+	return s
+}
+
+func newSharingResponse(tx *Transaction) *SharingResponse {
+	if tx.Sharing == nil {
+		return nil
+	}
+	response := &SharingResponse{
+		PaidBy:       tx.Sharing.PaidBy,
+		Method:       tx.Sharing.Method,
+		Participants: make([]ParticipantResponse, len(tx.Sharing.Participants)),
+	}
+	for i, cents := range tx.Sharing.owedCents(tx.Amount) {
+		p := tx.Sharing.Participants[i]
+		response.Participants[i] = ParticipantResponse{Member: p.Member, Shares: p.Shares, Owed: fromCents(cents)}
+	}
+	return response
+}
+
+type MemberBalanceResponse struct {
+	Member string  `json:"member"`
+	Paid   float64 `json:"paid"`
+	Owed   float64 `json:"owed"`
+	Net    float64 `json:"net"`
+}
+
+type SettlementResponse struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+type BalancesResponse struct {
+	Balances []MemberBalanceResponse `json:"balances"`
+	SettleUp []SettlementResponse    `json:"settle_up"`
+}
+
+type CreateSettlementRequest struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Date   string  `json:"date" format:"date"`
+}
+
+func (h *Handler) BalancesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	balances := h.ledger.Balances()
+	response := BalancesResponse{
+		Balances: make([]MemberBalanceResponse, len(balances)),
+		SettleUp: make([]SettlementResponse, 0),
+	}
+	for i, b := range balances {
+		response.Balances[i] = MemberBalanceResponse{Member: b.Member, Paid: b.Paid, Owed: b.Owed, Net: fromCents(toCents(b.Net()))}
+	}
+	for _, s := range SettleUp(balances) {
+		response.SettleUp = append(response.SettleUp, SettlementResponse{From: s.From, To: s.To, Amount: s.Amount})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) CreateSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateSettlementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON format")
+		return
+	}
+
+	date := today()
+	if req.Date != "" {
+		var err error
+		if date, err = time.Parse(dateLayout, req.Date); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date format, use YYYY-MM-DD")
+			return
+		}
+	}
+	if req.From == req.To {
+		writeErrorCode(w, http.StatusBadRequest, ErrCodeValidation, "cannot settle up with oneself")
+		return
+	}
+
+	tx := NewSettlement(uuid.New().String(), req.From, req.To, req.Amount, date)
+	if err := h.ledger.AddTransactionContext(r.Context(), tx); err != nil {
+		status, code := transactionErrorStatus(err)
+		writeErrorCode(w, status, code, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newTransactionResponse(tx))
+}
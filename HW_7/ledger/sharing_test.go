@@ -0,0 +1,212 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSharing_Owed(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		sharing Sharing
+		want    []int64
+	}{
+		{"equal", 90, Sharing{Method: ShareEqual, Participants: []Participant{{Member: "ann"}, {Member: "bob"}, {Member: "cid"}}}, []int64{3000, 3000, 3000}},
+		{"equal with remainder", 100, Sharing{Method: ShareEqual, Participants: []Participant{{Member: "ann"}, {Member: "bob"}, {Member: "cid"}}}, []int64{3334, 3333, 3333}},
+		{"by shares", 100, Sharing{Method: ShareByShares, Participants: []Participant{{Member: "ann", Shares: 2}, {Member: "bob", Shares: 1}}}, []int64{6667, 3333}},
+		{"exact", 50, Sharing{Method: ShareExact, Participants: []Participant{{Member: "ann", Amount: 12.5}, {Member: "bob", Amount: 37.5}}}, []int64{1250, 3750}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sharing.owedCents(tt.amount)
+			var sum int64
+			for i := range got {
+				sum += got[i]
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+			if sum != toCents(tt.amount) {
+				t.Errorf("Expected parts to sum to %.2f, got %.2f", tt.amount, fromCents(sum))
+			}
+		})
+	}
+}
+
+func TestTransaction_ValidateSharing(t *testing.T) {
+	tests := []struct {
+		name    string
+		txType  string
+		sharing Sharing
+		wantErr string
+	}{
+		{"valid", "expense", Sharing{PaidBy: "ann", Method: ShareEqual, Participants: []Participant{{Member: "ann"}, {Member: "bob"}}}, ""},
+		{"no payer", "expense", Sharing{Method: ShareEqual, Participants: []Participant{{Member: "bob"}}}, "sharing payer cannot be empty"},
+		{"no participants", "expense", Sharing{PaidBy: "ann", Method: ShareEqual}, "sharing needs at least one participant"},
+		{"duplicate participant", "expense", Sharing{PaidBy: "ann", Method: ShareEqual, Participants: []Participant{{Member: "bob"}, {Member: "bob"}}}, `duplicate participant "bob"`},
+		{"unknown method", "expense", Sharing{PaidBy: "ann", Method: "random", Participants: []Participant{{Member: "bob"}}}, "sharing method must be 'equal', 'shares' or 'exact'"},
+		{"zero shares", "expense", Sharing{PaidBy: "ann", Method: ShareByShares, Participants: []Participant{{Member: "bob"}}}, "participant shares must be positive"},
+		{"exact mismatch", "expense", Sharing{PaidBy: "ann", Method: ShareExact, Participants: []Participant{{Member: "bob", Amount: 40}}}, "participant amounts sum to 40.00 but amount is 60.00"},
+		{"shared income", "income", Sharing{PaidBy: "ann", Method: ShareEqual, Participants: []Participant{{Member: "bob"}}}, "income cannot be shared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := Transaction{Amount: 60, Category: "dining", Date: date("2024-01-01"), Type: tt.txType, Sharing: &tt.sharing}
+			err := tx.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func seedSharedLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	everyone := []Participant{{Member: "ann"}, {Member: "bob"}, {Member: "cid"}}
+	ledger := NewLedger()
+	txs := []*Transaction{
+		{ID: "dinner", Amount: 90, Category: "dining", Date: date("2024-03-01"), Type: "expense", Sharing: &Sharing{PaidBy: "ann", Method: ShareEqual, Participants: everyone}},
+		{ID: "cabin", Amount: 300, Category: "lodging", Date: date("2024-03-02"), Type: "expense", Sharing: &Sharing{PaidBy: "bob", Method: ShareByShares, Participants: []Participant{
+			{Member: "ann", Shares: 1}, {Member: "bob", Shares: 1}, {Member: "cid", Shares: 1},
+		}}},
+		{ID: "fuel", Amount: 60, Category: "transport", Date: date("2024-03-03"), Type: "expense", Sharing: &Sharing{PaidBy: "ann", Method: ShareExact, Participants: []Participant{
+			{Member: "cid", Amount: 60},
+		}}},
+		{ID: "groceries", Amount: 45, Category: "food", Date: date("2024-03-03"), Type: "expense"},
+	}
+	mustAdd(t, ledger, txs...)
+	return ledger
+}
+
+func TestLedger_BalancesAndSettleUp(t *testing.T) {
+	ledger := seedSharedLedger(t)
+
+	want := map[string]float64{"ann": 20, "bob": 170, "cid": -190}
+	balances := ledger.Balances()
+	if len(balances) != len(want) {
+		t.Fatalf("Expected %d members, got %+v", len(want), balances)
+	}
+	for _, b := range balances {
+		if !approx(b.Net(), want[b.Member]) {
+			t.Errorf("Expected %s net %.2f, got %.2f", b.Member, want[b.Member], b.Net())
+		}
+	}
+
+	plan := SettleUp(balances)
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 transfers, got %+v", plan)
+	}
+	if plan[0] != (Settlement{From: "cid", To: "bob", Amount: 170}) || plan[1] != (Settlement{From: "cid", To: "ann", Amount: 20}) {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+
+	for _, s := range plan {
+		if err := ledger.AddTransaction(NewSettlement("settle-"+s.To, s.From, s.To, s.Amount, date("2024-03-10"))); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for _, b := range ledger.Balances() {
+		if !approx(b.Net(), 0) {
+			t.Errorf("Expected %s to be settled, got %.2f", b.Member, b.Net())
+		}
+	}
+	if plan := SettleUp(ledger.Balances()); len(plan) != 0 {
+		t.Errorf("Expected nothing left to settle, got %+v", plan)
+	}
+
+	t.Run("settlements are neither income nor spending", func(t *testing.T) {
+		summary := ledger.Summary(date("2024-03-31"))
+		if summary.Income != 0 || summary.Expenses != 495 {
+			t.Errorf("Expected income 0 and expenses 495, got %.2f and %.2f", summary.Income, summary.Expenses)
+		}
+		if spent := ledger.GetCategorySpending(settlementCategory); spent != 0 {
+			t.Errorf("Expected no settlement spending, got %.2f", spent)
+		}
+	})
+}
+
+func TestBalanceHandlers(t *testing.T) {
+	handler := NewHandler(seedSharedLedger(t))
+
+	rr := httptest.NewRecorder()
+	handler.BalancesHandler(rr, httptest.NewRequest("GET", "/api/balances", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var balances BalancesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &balances); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(balances.Balances) != 3 || balances.Balances[2].Member != "cid" || balances.Balances[2].Net != -190 {
+		t.Errorf("Unexpected balances %+v", balances.Balances)
+	}
+	if len(balances.SettleUp) != 2 || balances.SettleUp[0].From != "cid" {
+		t.Errorf("Unexpected settle-up plan %+v", balances.SettleUp)
+	}
+
+	t.Run("transaction response shows owed parts", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ListTransactionsHandler(rr, httptest.NewRequest("GET", "/api/transactions?category=dining", nil))
+		var transactions []TransactionResponse
+		json.Unmarshal(rr.Body.Bytes(), &transactions)
+		if len(transactions) != 1 || transactions[0].Sharing == nil || transactions[0].Sharing.Participants[1].Owed != 30 {
+			t.Errorf("Unexpected transactions %+v", transactions)
+		}
+	})
+
+	t.Run("record settlement", func(t *testing.T) {
+		body := `{"from":"cid","to":"bob","amount":170,"date":"2024-03-10"}`
+		rr := httptest.NewRecorder()
+		handler.CreateSettlementHandler(rr, httptest.NewRequest("POST", "/api/balances/settlements", strings.NewReader(body)))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		var tx TransactionResponse
+		json.Unmarshal(rr.Body.Bytes(), &tx)
+		if tx.Type != "transfer" || tx.Category != settlementCategory || tx.Sharing.PaidBy != "cid" {
+			t.Errorf("Unexpected settlement %+v", tx)
+		}
+
+		rr = httptest.NewRecorder()
+		handler.BalancesHandler(rr, httptest.NewRequest("GET", "/api/balances", nil))
+		json.Unmarshal(rr.Body.Bytes(), &balances)
+		if len(balances.SettleUp) != 1 || balances.SettleUp[0] != (SettlementResponse{From: "cid", To: "ann", Amount: 20}) {
+			t.Errorf("Unexpected settle-up plan %+v", balances.SettleUp)
+		}
+	})
+
+	t.Run("invalid settlement", func(t *testing.T) {
+		for _, body := range []string{`{"from":"cid","to":"bob","amount":0}`, `{"from":"","to":"bob","amount":5}`} {
+			rr := httptest.NewRecorder()
+			handler.CreateSettlementHandler(rr, httptest.NewRequest("POST", "/api/balances/settlements", strings.NewReader(body)))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+
+	t.Run("settlement in a closed period", func(t *testing.T) {
+		if _, err := handler.ledger.ClosePeriod("2024-02", "alice"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body := `{"from":"cid","to":"ann","amount":20,"date":"2024-02-20"}`
+		rr := httptest.NewRecorder()
+		handler.CreateSettlementHandler(rr, httptest.NewRequest("POST", "/api/balances/settlements", strings.NewReader(body)))
+		var errorResp ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &errorResp)
+		if rr.Code != http.StatusConflict || errorResp.Code != ErrCodePeriodLocked {
+			t.Errorf("Expected status %d with %s, got %d: %s", http.StatusConflict, ErrCodePeriodLocked, rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package ledger
+
+// splitTolerance is how far split lines may sum from the transaction amount,
+// to absorb rounding of cents.
+const splitTolerance = 0.005
+
+// Split is one line of a transaction spread over several categories, such as
+// the food and household parts of a supermarket receipt.
+type Split struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// Lines returns the transaction's splits, or a single line covering the whole
+// amount when it is not split.
+func (t *Transaction) Lines() []Split {
+	if len(t.Splits) == 0 {
+		return []Split{{Category: t.Category, Amount: t.Amount}}
+	}
+	return t.Splits
+}
+
+// byCategory merges lines sharing a category, in order of first appearance.
+func (t *Transaction) byCategory() []Split {
+	lines := t.Lines()
+	merged := make([]Split, 0, len(lines))
+	index := make(map[string]int, len(lines))
+	for _, line := range lines {
+		if i, exists := index[line.Category]; exists {
+			merged[i].Amount += line.Amount
+			continue
+		}
+		index[line.Category] = len(merged)
+		merged = append(merged, Split{Category: line.Category, Amount: line.Amount})
+	}
+	return merged
+}
+
+// inCategory returns the part of t booked to category as a transaction of its
+// own, or nil when no line uses the category.
+func (t *Transaction) inCategory(category string) *Transaction {
+	if len(t.Splits) == 0 {
+		if t.Category == category {
+			return t
+		}
+		return nil
+	}
+
+	for _, line := range t.byCategory() {
+		if line.Category == category {
+			part := *t
+			part.Category = category
+			part.Amount = line.Amount
+			part.Splits = nil
+			return &part
+		}
+	}
+	return nil
+}
+
+func (t *Transaction) hasCategory(category string) bool {
+	for _, line := range t.Lines() {
+		if line.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSplit files a split transaction without a category under its largest
+// line, which is what single-category consumers such as the anomaly detector
+// see.
+func (t *Transaction) fileSplit() {
+	if t.Category != "" || len(t.Splits) == 0 {
+		return
+	}
+	largest := t.Splits[0]
+	for _, line := range t.Splits[1:] {
+		if line.Amount > largest.Amount {
+			largest = line
+		}
+	}
+	t.Category = largest.Category
+}
@@ -0,0 +1,158 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jukov801/Golang_MIPT/HW_6/ledgerpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func receipt(id string, food, household float64) *Transaction {
+	return &Transaction{
+		ID:     id,
+		Amount: food + household,
+		Date:   date("2024-03-02"),
+		Type:   "expense",
+		Splits: []Split{
+			{Category: "food", Amount: food},
+			{Category: "household", Amount: household, Note: "detergent"},
+		},
+	}
+}
+
+func TestLedger_SplitTransactions(t *testing.T) {
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+	ledger.SetBudget(&Budget{Category: "household", Limit: 50})
+
+	first := receipt("1", 60, 30)
+	if err := ledger.AddTransaction(first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Category != "food" {
+		t.Errorf("Expected split to be filed under its largest line, got %q", first.Category)
+	}
+
+	t.Run("spending is booked per line", func(t *testing.T) {
+		if got := ledger.GetCategorySpending("food"); got != 60 {
+			t.Errorf("Expected food spending 60, got %v", got)
+		}
+		if got := ledger.GetCategoryPeriodSpending("household", date("2024-03-01")); got != 30 {
+			t.Errorf("Expected household spending 30, got %v", got)
+		}
+	})
+
+	t.Run("any line over budget rejects the transaction", func(t *testing.T) {
+		var rejected []string
+		ledger.SetHooks(Hooks{BudgetExceeded: func(category string, amount float64) {
+			rejected = append(rejected, category)
+		}})
+		defer ledger.SetHooks(Hooks{})
+
+		if err := ledger.AddTransaction(receipt("2", 10, 25)); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("Expected %v, got %v", ErrBudgetExceeded, err)
+		}
+		if len(rejected) != 1 || rejected[0] != "household" {
+			t.Errorf("Expected household rejection, got %v", rejected)
+		}
+		if got := ledger.GetCategorySpending("food"); got != 60 {
+			t.Errorf("Expected food spending unchanged, got %v", got)
+		}
+	})
+
+	t.Run("lines of the same category add up", func(t *testing.T) {
+		tx := &Transaction{ID: "3", Amount: 50, Date: date("2024-03-03"), Type: "expense", Splits: []Split{
+			{Category: "food", Amount: 25}, {Category: "food", Amount: 25},
+		}}
+		if err := ledger.AddTransaction(tx); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Expected %v, got %v", ErrBudgetExceeded, err)
+		}
+	})
+
+	t.Run("update replaces the old lines", func(t *testing.T) {
+		if err := ledger.UpdateTransaction(receipt("1", 90, 10)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if food, household := ledger.GetCategorySpending("food"), ledger.GetCategorySpending("household"); food != 90 || household != 10 {
+			t.Errorf("Expected 90/10 after update, got %v/%v", food, household)
+		}
+		if err := ledger.CheckConsistency(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("filter matches any line", func(t *testing.T) {
+		if got := ledger.FilterTransactions(TransactionFilter{Category: "household"}); len(got) != 1 {
+			t.Errorf("Expected split transaction to match household, got %d", len(got))
+		}
+	})
+
+	t.Run("delete removes every line", func(t *testing.T) {
+		if err := ledger.DeleteTransaction("1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if food, household := ledger.GetCategorySpending("food"), ledger.GetCategorySpending("household"); food != 0 || household != 0 {
+			t.Errorf("Expected no spending after delete, got %v/%v", food, household)
+		}
+	})
+}
+
+func TestSplitTransaction_RoundTrip(t *testing.T) {
+	ledger := NewLedger()
+	handler := NewHandler(ledger)
+
+	body := `{"amount":90,"date":"2024-03-02","type":"expense","splits":[{"category":"food","amount":60},{"category":"household","amount":30,"note":"detergent"}]}`
+	rr := httptest.NewRecorder()
+	handler.CreateTransactionHandler(rr, httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created TransactionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if created.Category != "food" || len(created.Splits) != 2 || created.Splits[1].Note != "detergent" {
+		t.Errorf("Unexpected response %+v", created)
+	}
+
+	t.Run("snapshot", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ledger.WriteSnapshot(&buf); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		restored := NewLedger()
+		if err := restored.ReadSnapshot(&buf); err != nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		if got := restored.GetCategorySpending("household"); got != 30 {
+			t.Errorf("Expected restored household spending 30, got %v", got)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		client := newGRPCClient(t, ledger)
+		tx, err := client.CreateTransaction(context.Background(), &ledgerpb.CreateTransactionRequest{
+			Amount: 20,
+			Date:   timestamppb.New(time.Now().Add(-time.Hour)),
+			Type:   "expense",
+			Splits: []*ledgerpb.Split{{Category: "food", Amount: 5}, {Category: "household", Amount: 15}},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if tx.GetCategory() != "household" || len(tx.GetSplits()) != 2 {
+			t.Errorf("Unexpected transaction %v", tx)
+		}
+		if got := ledger.GetCategorySpending("household"); got != 45 {
+			t.Errorf("Expected household spending 45, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Storage persists a ledger between runs. Load fills the ledger at startup;
+// Flush writes its current state, e.g. on shutdown. Ping reports whether the
+// backing store is reachable, for readiness checks.
+type Storage interface {
+	Load(ctx context.Context, l *Ledger) error
+	Flush(ctx context.Context, l *Ledger) error
+	Ping(ctx context.Context) error
+}
+
+// OpenStorage picks a Storage from a DSN: "memory:" (or empty) keeps state in
+// memory only, "file:/path/to/ledger.json" snapshots it to a JSON file.
+func OpenStorage(dsn string) (Storage, error) {
+	if dsn == "" {
+		return memoryStorage{}, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage DSN: %w", err)
+	}
+
+	switch u.Scheme {
+	case "memory":
+		return memoryStorage{}, nil
+	case "file":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, errors.New("file storage DSN needs a path")
+		}
+		return &FileStorage{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q", u.Scheme)
+	}
+}
+
+type memoryStorage struct{}
+
+func (memoryStorage) Load(context.Context, *Ledger) error  { return nil }
+func (memoryStorage) Flush(context.Context, *Ledger) error { return nil }
+func (memoryStorage) Ping(context.Context) error           { return nil }
+
+type FileStorage struct {
+	Path string
+}
+
+func (s *FileStorage) Load(ctx context.Context, l *Ledger) (err error) {
+	_, span := startSpan(ctx, "FileStorage.Load")
+	defer func() { endSpan(span, err) }()
+
+	f, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.ReadSnapshot(f)
+}
+
+// Flush writes to a temporary file and renames it over the snapshot, so a
+// crash mid-write never leaves a truncated file behind.
+func (s *FileStorage) Flush(ctx context.Context, l *Ledger) (err error) {
+	_, span := startSpan(ctx, "FileStorage.Flush")
+	defer func() { endSpan(span, err) }()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := l.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// Ping checks that the snapshot directory exists, since Flush must be able to
+// create files there.
+func (s *FileStorage) Ping(ctx context.Context) error {
+	dir := filepath.Dir(s.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+type snapshot struct {
+	Transactions    []*Transaction    `json:"transactions"`
+	Budgets         []*Budget         `json:"budgets"`
+	Goals           []*Goal           `json:"goals,omitempty"`
+	Reconciliations []*Reconciliation `json:"reconciliations,omitempty"`
+	ClosedPeriods   []ClosedPeriod    `json:"closed_periods,omitempty"`
+	PeriodAudit     []PeriodEvent     `json:"period_audit,omitempty"`
+}
+
+// WriteSnapshot writes the whole ledger as of one moment: it is encoded under
+// a single read lock, so no change lands between its parts.
+func (l *Ledger) WriteSnapshot(w io.Writer) error {
+	l.mu.RLock()
+	snap := snapshot{
+		Transactions:    slices.Clone(l.Transactions),
+		Budgets:         make([]*Budget, 0, len(l.Budgets)),
+		Goals:           l.sortedGoals(),
+		Reconciliations: l.sortedReconciliations(),
+		ClosedPeriods:   l.sortedClosedPeriods(),
+		PeriodAudit:     l.periodAudit,
+	}
+	for _, budget := range l.Budgets {
+		snap.Budgets = append(snap.Budgets, budget)
+	}
+	data, err := json.Marshal(snap)
+	l.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadSnapshot replaces the ledger contents with a snapshot written by
+// WriteSnapshot.
+func (l *Ledger) ReadSnapshot(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, tx := range l.Transactions {
+		l.detector.Forget(tx)
+	}
+	l.Transactions = make([]*Transaction, 0, len(snap.Transactions))
+	l.Budgets = make(map[string]*Budget, len(snap.Budgets))
+	l.Goals = make(map[string]*Goal, len(snap.Goals))
+	l.spending = newSpendingIndex()
+
+	for _, tx := range snap.Transactions {
+		if tx.Status == "" {
+			tx.Status = StatusUncleared
+		}
+		l.Transactions = append(l.Transactions, tx)
+		l.spending.add(tx)
+		l.detector.Observe(tx)
+	}
+	for _, budget := range snap.Budgets {
+		l.Budgets[budget.Category] = budget
+		l.budgetChanged(budget.Category, budget)
+	}
+	l.tags = newTagIndex(l.Transactions)
+	for _, goal := range snap.Goals {
+		l.Goals[goal.ID] = goal
+	}
+	l.reconciliations = make(map[string]*Reconciliation, len(snap.Reconciliations))
+	for _, rec := range snap.Reconciliations {
+		l.reconciliations[rec.ID] = rec
+	}
+	l.closedPeriods = make(map[string]*ClosedPeriod, len(snap.ClosedPeriods))
+	for _, closed := range snap.ClosedPeriods {
+		l.closedPeriods[closed.Period] = &closed
+	}
+	l.periodAudit = snap.PeriodAudit
+	return nil
+}
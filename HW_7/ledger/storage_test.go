@@ -0,0 +1,128 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenStorage(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{dsn: "", want: "memory"},
+		{dsn: "memory:", want: "memory"},
+		{dsn: "file:/var/lib/ledger.json", want: "/var/lib/ledger.json"},
+		{dsn: "file:ledger.json", want: "ledger.json"},
+		{dsn: "file:", wantErr: true},
+		{dsn: "postgres://localhost/ledger", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			storage, err := OpenStorage(tt.dsn)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := "memory"
+			if fs, ok := storage.(*FileStorage); ok {
+				got = fs.Path
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileStorage_FlushAndLoad(t *testing.T) {
+	ctx := context.Background()
+	storage := &FileStorage{Path: filepath.Join(t.TempDir(), "ledger.json")}
+
+	empty := NewLedger()
+	if err := storage.Load(ctx, empty); err != nil {
+		t.Fatalf("Expected missing snapshot to load as empty, got %v", err)
+	}
+
+	original := NewLedger()
+	original.SetBudget(&Budget{Category: "food", Limit: 100})
+	original.AddTransaction(&Transaction{ID: "1", Amount: 40, Category: "food", Type: "expense", Date: time.Now()})
+	original.AddTransaction(&Transaction{ID: "2", Amount: 500, Category: "salary", Type: "income", Date: time.Now()})
+	original.AddGoal(&Goal{ID: "car", Name: "Car", Target: 1000, Category: "salary", Start: date("2024-01-01"), Deadline: date("2030-01-01")})
+
+	if err := storage.Flush(ctx, original); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	restored := NewLedger()
+	if err := storage.Load(ctx, restored); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if got := len(restored.ListTransactions()); got != 2 {
+		t.Errorf("Expected 2 transactions, got %d", got)
+	}
+	if budget, ok := restored.GetBudget("food"); !ok || budget.Limit != 100 {
+		t.Errorf("Expected food budget to be restored, got %v", budget)
+	}
+	if goal, ok := restored.GetGoal("car"); !ok || !goal.Deadline.Equal(date("2030-01-01")) {
+		t.Errorf("Expected car goal to be restored, got %v", goal)
+	}
+	if got := restored.GetCategorySpending("food"); got != 40 {
+		t.Errorf("Expected restored spending 40, got %v", got)
+	}
+	if err := restored.AddTransaction(&Transaction{ID: "3", Amount: 70, Category: "food", Type: "expense", Date: time.Now()}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected restored budget to be enforced, got %v", err)
+	}
+}
+
+func TestLedger_WriteSnapshotConsistent(t *testing.T) {
+	ledger := NewLedger()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 2000 {
+			ledger.ClosePeriod("2024-01", "alice")
+			ledger.ReopenPeriod("2024-01", "alice", "fix")
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		var buf bytes.Buffer
+		if err := ledger.WriteSnapshot(&buf); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var snap snapshot
+		json.Unmarshal(buf.Bytes(), &snap)
+		closed := 0
+		for _, event := range snap.PeriodAudit {
+			if event.Action == PeriodClosed {
+				closed++
+			} else {
+				closed--
+			}
+		}
+		if closed != len(snap.ClosedPeriods) {
+			t.Fatalf("Expected %d closed periods from the audit, got %d", closed, len(snap.ClosedPeriods))
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package ledger
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxTags             = 10
+	maxTagLength        = 32
+	maxMetadataEntries  = 20
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 256
+)
+
+func validateTags(tags []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("at most %d tags allowed", maxTags)
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == "" || len(tag) > maxTagLength {
+			return fmt.Errorf("tags must be 1 to %d characters", maxTagLength)
+		}
+		if strings.ContainsFunc(tag, func(r rune) bool { return r == ' ' || r == ',' || r < 0x20 }) {
+			return fmt.Errorf("tag %q cannot contain spaces or commas", tag)
+		}
+		if seen[tag] {
+			return fmt.Errorf("duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("at most %d metadata entries allowed", maxMetadataEntries)
+	}
+	for key, value := range metadata {
+		if key == "" || len(key) > maxMetadataKeyLen {
+			return fmt.Errorf("metadata keys must be 1 to %d characters", maxMetadataKeyLen)
+		}
+		if len(value) > maxMetadataValueLen {
+			return fmt.Errorf("metadata value for %q exceeds %d characters", key, maxMetadataValueLen)
+		}
+	}
+	return nil
+}
+
+func (t *Transaction) hasTag(tag string) bool {
+	for _, candidate := range t.Tags {
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// tagIndex lists the transactions carrying each tag in ledger order, so tag
+// filters and reports do not scan the whole ledger.
+type tagIndex map[string][]*Transaction
+
+func newTagIndex(transactions []*Transaction) tagIndex {
+	idx := make(tagIndex)
+	for _, tx := range transactions {
+		idx.add(tx)
+	}
+	return idx
+}
+
+func (idx tagIndex) add(tx *Transaction) {
+	for _, tag := range tx.Tags {
+		idx[tag] = append(idx[tag], tx)
+	}
+}
+
+func (idx tagIndex) remove(tx *Transaction) {
+	for _, tag := range tx.Tags {
+		txs := idx[tag]
+		for i, candidate := range txs {
+			if candidate == tx {
+				idx[tag] = append(txs[:i:i], txs[i+1:]...)
+				break
+			}
+		}
+		if len(idx[tag]) == 0 {
+			delete(idx, tag)
+		}
+	}
+}
+
+// replace swaps old for tx, which took its place in transactions. Tags new to
+// tx are re-collected from transactions to keep ledger order.
+func (idx tagIndex) replace(old, tx *Transaction, transactions []*Transaction) {
+	idx.remove(old)
+	for _, tag := range tx.Tags {
+		txs := make([]*Transaction, 0, len(idx[tag])+1)
+		for _, candidate := range transactions {
+			if candidate.hasTag(tag) {
+				txs = append(txs, candidate)
+			}
+		}
+		idx[tag] = txs
+	}
+}
+
+// TagTotal sums the transactions carrying a tag, with expenses broken down by
+// category so a trip can be totalled across categories.
+type TagTotal struct {
+	Tag        string
+	Count      int
+	Income     float64
+	Expenses   float64
+	Categories map[string]float64
+}
+
+// TagReport totals the posted transactions matching f for each tag, ordered by
+// tag. Limit and Offset are ignored.
+func (l *Ledger) TagReport(f TransactionFilter) []TagTotal {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	f.Limit, f.Offset = 0, 0
+	tags := make([]string, 0, len(l.tags))
+	for tag := range l.tags {
+		if f.Tag == "" || tag == f.Tag {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	report := make([]TagTotal, 0, len(tags))
+	for _, tag := range tags {
+		total := TagTotal{Tag: tag, Categories: make(map[string]float64)}
+		for _, tx := range l.tags[tag] {
+			if !f.Match(tx) || tx.Status == StatusPending {
+				continue
+			}
+			total.Count++
+			if tx.Type == "income" {
+				total.Income += tx.Amount
+				continue
+			}
+			if tx.Type != "expense" {
+				continue
+			}
+			total.Expenses += tx.Amount
+			for _, line := range tx.Lines() {
+				total.Categories[line.Category] += line.Amount
+			}
+		}
+		if total.Count > 0 {
+			report = append(report, total)
+		}
+	}
+	return report
+}
+
+type TagReportResponse struct {
+	Tag        string             `json:"tag"`
+	Count      int                `json:"count"`
+	Income     float64            `json:"income"`
+	Expenses   float64            `json:"expenses"`
+	Net        float64            `json:"net"`
+	Categories map[string]float64 `json:"categories"`
+}
+
+type TagReportQuery struct {
+	Tag      string    `query:"tag"`
+	Category string    `query:"category"`
+	Type     string    `query:"type" enum:"income,expense,transfer"`
+	From     time.Time `query:"from" format:"date"`
+	To       time.Time `query:"to" format:"date"`
+}
+
+func (h *Handler) TagReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter, err := ParseTransactionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report := h.ledger.TagReport(filter)
+	response := make([]TagReportResponse, len(report))
+	for i, total := range report {
+		response[i] = TagReportResponse{
+			Tag:        total.Tag,
+			Count:      total.Count,
+			Income:     total.Income,
+			Expenses:   total.Expenses,
+			Net:        total.Income - total.Expenses,
+			Categories: total.Categories,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTransaction_ValidateTagsAndMetadata(t *testing.T) {
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = "tag-" + strconv.Itoa(i)
+	}
+	bigMetadata := make(map[string]string, maxMetadataEntries+1)
+	for i := 0; i <= maxMetadataEntries; i++ {
+		bigMetadata["key-"+strconv.Itoa(i)] = "value"
+	}
+
+	tests := []struct {
+		name     string
+		tags     []string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{"valid", []string{"vacation-2026", "reimbursable"}, map[string]string{"merchant": "Hotel Lux"}, false},
+		{"too many tags", tooMany, nil, true},
+		{"empty tag", []string{""}, nil, true},
+		{"long tag", []string{strings.Repeat("x", maxTagLength+1)}, nil, true},
+		{"tag with space", []string{"summer trip"}, nil, true},
+		{"duplicate tag", []string{"trip", "trip"}, nil, true},
+		{"too many metadata entries", nil, bigMetadata, true},
+		{"empty metadata key", nil, map[string]string{"": "x"}, true},
+		{"long metadata value", nil, map[string]string{"note": strings.Repeat("x", maxMetadataValueLen+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := Transaction{Amount: 10, Category: "food", Date: date("2024-01-01"), Type: "expense", Tags: tt.tags, Metadata: tt.metadata}
+			if err := tx.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func seedTagLedger(t *testing.T) *Ledger {
+	t.Helper()
+
+	ledger := NewLedger()
+	txs := []*Transaction{
+		{ID: "flight", Amount: 400, Category: "transport", Date: date("2024-07-01"), Type: "expense", Tags: []string{"vacation-2024"}},
+		{ID: "hotel", Amount: 600, Category: "lodging", Date: date("2024-07-02"), Type: "expense", Tags: []string{"vacation-2024", "reimbursable"}},
+		{ID: "groceries", Amount: 80, Category: "food", Date: date("2024-07-03"), Type: "expense"},
+		{ID: "dinner", Amount: 120, Date: date("2024-07-03"), Type: "expense", Tags: []string{"vacation-2024"}, Splits: []Split{
+			{Category: "food", Amount: 90}, {Category: "drinks", Amount: 30},
+		}},
+		{ID: "refund", Amount: 600, Category: "lodging", Date: date("2024-07-20"), Type: "income", Tags: []string{"reimbursable"}},
+	}
+	mustAdd(t, ledger, txs...)
+	return ledger
+}
+
+func ids(txs []*Transaction) string {
+	parts := make([]string, len(txs))
+	for i, tx := range txs {
+		parts[i] = tx.ID
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestLedger_TagIndex(t *testing.T) {
+	ledger := seedTagLedger(t)
+
+	if got := ids(ledger.FilterTransactions(TransactionFilter{Tag: "vacation-2024"})); got != "flight,hotel,dinner" {
+		t.Errorf("Expected vacation transactions in ledger order, got %s", got)
+	}
+
+	updated := &Transaction{ID: "groceries", Amount: 80, Category: "food", Date: date("2024-07-03"), Type: "expense", Tags: []string{"vacation-2024"}}
+	if err := ledger.UpdateTransaction(updated); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := ids(ledger.FilterTransactions(TransactionFilter{Tag: "vacation-2024"})); got != "flight,hotel,groceries,dinner" {
+		t.Errorf("Expected retagged transaction in ledger order, got %s", got)
+	}
+
+	ledger.DeleteTransaction("hotel")
+	if got := ids(ledger.FilterTransactions(TransactionFilter{Tag: "reimbursable"})); got != "refund" {
+		t.Errorf("Expected deleted transaction to leave the index, got %s", got)
+	}
+	if got := ids(ledger.FilterTransactions(TransactionFilter{Tag: "vacation-2024", Limit: 1, Offset: 1})); got != "groceries" {
+		t.Errorf("Expected paging within the tag, got %s", got)
+	}
+}
+
+func TestLedger_TagReport(t *testing.T) {
+	report := seedTagLedger(t).TagReport(TransactionFilter{})
+	if len(report) != 2 {
+		t.Fatalf("Expected 2 tags, got %+v", report)
+	}
+
+	reimbursable, vacation := report[0], report[1]
+	if reimbursable.Tag != "reimbursable" || reimbursable.Income != 600 || reimbursable.Expenses != 600 {
+		t.Errorf("Unexpected reimbursable total %+v", reimbursable)
+	}
+
+	want := map[string]float64{"transport": 400, "lodging": 600, "food": 90, "drinks": 30}
+	if vacation.Tag != "vacation-2024" || vacation.Count != 3 || vacation.Expenses != 1120 {
+		t.Errorf("Unexpected vacation total %+v", vacation)
+	}
+	for category, amount := range want {
+		if vacation.Categories[category] != amount {
+			t.Errorf("Expected %s %.2f, got %.2f", category, amount, vacation.Categories[category])
+		}
+	}
+}
+
+func TestTagHandlers(t *testing.T) {
+	handler := NewHandler(seedTagLedger(t))
+
+	t.Run("filter by tag", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ListTransactionsHandler(rr, httptest.NewRequest("GET", "/api/transactions?tag=reimbursable", nil))
+
+		var response []TransactionResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(response) != 2 || response[0].ID != "hotel" || response[0].Tags[1] != "reimbursable" {
+			t.Errorf("Unexpected transactions %+v", response)
+		}
+	})
+
+	t.Run("report for one tag and period", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.TagReportHandler(rr, httptest.NewRequest("GET", "/api/reports/tags?tag=reimbursable&to=2024-07-10", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var response []TagReportResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(response) != 1 || response[0].Net != -600 || response[0].Categories["lodging"] != 600 {
+			t.Errorf("Unexpected report %+v", response)
+		}
+	})
+}
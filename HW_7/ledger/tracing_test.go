@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+	return byName
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ledger := NewLedger()
+	ledger.SetBudget(&Budget{Category: "food", Limit: 100})
+	handler := NewHandler(ledger)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/transactions", handler.CreateTransactionHandler)
+	server := TracingMiddleware(mux)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	t.Run("spans nest under the propagated trace", func(t *testing.T) {
+		body := `{"amount":50,"category":"food","date":"2024-01-15","type":"expense"}`
+		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body))
+		req.Header.Set("traceparent", traceparent)
+		server.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := spansByName(recorder.Ended())
+		httpSpan, add := spans["POST /api/transactions"], spans["Ledger.AddTransaction"]
+		check, store := spans["Ledger.checkBudget"], spans["Ledger.store"]
+		if httpSpan == nil || add == nil || check == nil || store == nil {
+			t.Fatalf("Expected HTTP, AddTransaction, checkBudget and store spans, got %v", spans)
+		}
+
+		if got := httpSpan.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected propagated trace ID, got %s", got)
+		}
+		if got := httpSpan.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+			t.Errorf("Expected remote parent span 00f067aa0ba902b7, got %s", got)
+		}
+		if add.Parent().SpanID() != httpSpan.SpanContext().SpanID() {
+			t.Error("Expected AddTransaction span to be a child of the HTTP span")
+		}
+		if check.Parent().SpanID() != add.SpanContext().SpanID() || store.Parent().SpanID() != add.SpanContext().SpanID() {
+			t.Error("Expected budget check and store spans to be children of AddTransaction")
+		}
+	})
+
+	t.Run("budget rejection marks spans as failed", func(t *testing.T) {
+		recorder.Reset()
+		body := `{"amount":80,"category":"food","date":"2024-01-15","type":"expense"}`
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body)))
+
+		spans := spansByName(recorder.Ended())
+		if _, stored := spans["Ledger.store"]; stored {
+			t.Error("Expected no store span for a rejected transaction")
+		}
+		check := spans["Ledger.checkBudget"]
+		if check == nil || !strings.HasPrefix(check.Status().Description, ErrBudgetExceeded.Error()) {
+			t.Errorf("Expected checkBudget span to record %v", ErrBudgetExceeded)
+		}
+	})
+
+	t.Run("request log carries the span", func(t *testing.T) {
+		recorder.Reset()
+		logs := captureLogs(t)
+		body := `{"amount":10,"category":"food","date":"2024-01-15","type":"expense"}`
+		req := httptest.NewRequest("POST", "/api/transactions", bytes.NewBufferString(body))
+		req.Header.Set("traceparent", traceparent)
+		LoggingMiddleware(server).ServeHTTP(httptest.NewRecorder(), req)
+
+		httpSpan := spansByName(recorder.Ended())["POST /api/transactions"]
+		if httpSpan == nil {
+			t.Fatal("Expected the HTTP span to keep the route name")
+		}
+		entries := logEntries(t, logs)
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 log entry, got %d", len(entries))
+		}
+		if entries[0]["trace_id"] != httpSpan.SpanContext().TraceID().String() || entries[0]["span_id"] != httpSpan.SpanContext().SpanID().String() {
+			t.Errorf("Expected the request line to carry the HTTP span, got %v", entries[0])
+		}
+	})
+}
+
+func TestInjectTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	t.Cleanup(upstream.Close)
+
+	req, _ := http.NewRequest("GET", upstream.URL, nil)
+	req, end := InjectTraceContext(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	end(resp.StatusCode, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 client span, got %d", len(spans))
+	}
+	sc := spans[0].SpanContext()
+	if want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("Expected traceparent %s, got %s", want, traceparent)
+	}
+}
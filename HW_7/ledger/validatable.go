@@ -2,45 +2,262 @@ package ledger
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"time"
+	"unicode/utf8"
 )
 
 type Validatable interface {
 	Validate() error
 }
 
-func (t *Transaction) Validate() error {
-	if t.Amount <= 0 {
-		return errors.New("amount must be positive")
-	}
+// Rule is one named check of a validation policy. The name lets a policy
+// replace or drop the rule.
+type Rule[T any] struct {
+	Name  string
+	Check func(T) error
+}
 
-	if t.Category == "" {
-		return errors.New("category cannot be empty")
+// Rules run in order and stop at the first failure.
+type Rules[T any] []Rule[T]
+
+func (rs Rules[T]) Validate(v T) error {
+	for _, rule := range rs {
+		if err := rule.Check(v); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if t.Date.IsZero() {
-		return errors.New("date cannot be zero")
+// With returns a copy of the rules where each given rule replaces the rule of
+// the same name, or is appended if there is none.
+func (rs Rules[T]) With(rules ...Rule[T]) Rules[T] {
+	result := slices.Clone(rs)
+	for _, rule := range rules {
+		i := slices.IndexFunc(result, func(r Rule[T]) bool { return r.Name == rule.Name })
+		if i < 0 {
+			result = append(result, rule)
+		} else {
+			result[i] = rule
+		}
 	}
+	return result
+}
+
+// Without returns a copy of the rules minus those named.
+func (rs Rules[T]) Without(names ...string) Rules[T] {
+	return slices.DeleteFunc(slices.Clone(rs), func(r Rule[T]) bool { return slices.Contains(names, r.Name) })
+}
+
+// Names of the rules that take parameters; the default policy uses
+// RuleDateWindow to reject future dates.
+const (
+	RuleDateWindow        = "date_window"
+	RuleMaxAmount         = "max_amount"
+	RuleAllowedCategories = "allowed_categories"
+	RuleMaxDescription    = "max_description_length"
+)
+
+// ValidationPolicy holds the rules a ledger checks before storing
+// transactions and budgets.
+type ValidationPolicy struct {
+	Transaction Rules[*Transaction]
+	Budget      Rules[*Budget]
+}
 
-	if t.Date.After(time.Now()) {
-		return errors.New("date cannot be in the future")
+func DefaultValidationPolicy() ValidationPolicy {
+	return ValidationPolicy{
+		Transaction: Rules[*Transaction]{
+			{"amount", func(t *Transaction) error {
+				if t.Amount <= 0 {
+					return errors.New("amount must be positive")
+				}
+				return nil
+			}},
+			{"category", func(t *Transaction) error {
+				if t.Category == "" {
+					return errors.New("category cannot be empty")
+				}
+				return nil
+			}},
+			{"date", func(t *Transaction) error {
+				if t.Date.IsZero() {
+					return errors.New("date cannot be zero")
+				}
+				return nil
+			}},
+			DateWindow(0, 0),
+			{"type", func(t *Transaction) error {
+				if t.Type != "income" && t.Type != "expense" && t.Type != "transfer" {
+					return errors.New("type must be 'income', 'expense' or 'transfer'")
+				}
+				return nil
+			}},
+			// Transactions only become reconciled by completing a reconciliation.
+			{"status", func(t *Transaction) error {
+				if t.Status != "" && t.Status != StatusUncleared && t.Status != StatusCleared {
+					return errors.New("status must be 'uncleared' or 'cleared'")
+				}
+				return nil
+			}},
+			{"tags", func(t *Transaction) error { return validateTags(t.Tags) }},
+			{"metadata", func(t *Transaction) error { return validateMetadata(t.Metadata) }},
+			{"splits", validateSplits},
+			{"sharing", func(t *Transaction) error {
+				if t.Sharing == nil {
+					return nil
+				}
+				if t.Type == "income" {
+					return errors.New("income cannot be shared")
+				}
+				return t.Sharing.validate(t.Amount)
+			}},
+		},
+		Budget: Rules[*Budget]{
+			{"limit", func(b *Budget) error {
+				if b.Limit <= 0 {
+					return errors.New("limit must be positive")
+				}
+				return nil
+			}},
+			{"category", func(b *Budget) error {
+				if b.Category == "" {
+					return errors.New("category cannot be empty")
+				}
+				return nil
+			}},
+		},
 	}
+}
+
+var defaultPolicy = DefaultValidationPolicy()
+
+// SetValidationPolicy replaces the rules checked when transactions and
+// budgets are added or updated. Stored entries are not rechecked.
+func (l *Ledger) SetValidationPolicy(p ValidationPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	if t.Type != "income" && t.Type != "expense" {
-		return errors.New("type must be 'income' or 'expense'")
+	l.policy = p
+}
+
+func validateSplits(t *Transaction) error {
+	if len(t.Splits) == 0 {
+		return nil
 	}
 
+	var sum float64
+	for _, line := range t.Splits {
+		if line.Amount <= 0 {
+			return errors.New("split amount must be positive")
+		}
+		if line.Category == "" {
+			return errors.New("split category cannot be empty")
+		}
+		sum += line.Amount
+	}
+	if math.Abs(sum-t.Amount) > splitTolerance {
+		return fmt.Errorf("splits sum to %.2f but amount is %.2f", sum, t.Amount)
+	}
 	return nil
 }
 
+// DateWindow accepts dates at most maxAge in the past and maxAhead in the
+// future of the time of the check. A zero maxAge leaves the past open; a zero
+// maxAhead rejects any future date.
+func DateWindow(maxAge, maxAhead time.Duration) Rule[*Transaction] {
+	return Rule[*Transaction]{RuleDateWindow, func(t *Transaction) error {
+		now := time.Now()
+		if t.Date.After(now.Add(maxAhead)) {
+			if maxAhead == 0 {
+				return errors.New("date cannot be in the future")
+			}
+			return fmt.Errorf("date cannot be more than %s ahead", days(maxAhead))
+		}
+		if maxAge > 0 && t.Date.Before(now.Add(-maxAge)) {
+			return fmt.Errorf("date cannot be more than %s ago", days(maxAge))
+		}
+		return nil
+	}}
+}
+
+func days(d time.Duration) string {
+	if n := int(d / (24 * time.Hour)); n != 1 {
+		return fmt.Sprintf("%d days", n)
+	}
+	return "1 day"
+}
+
+func MaxAmount(limit float64) Rule[*Transaction] {
+	return Rule[*Transaction]{RuleMaxAmount, func(t *Transaction) error {
+		if t.Amount > limit {
+			return fmt.Errorf("amount cannot exceed %.2f", limit)
+		}
+		return nil
+	}}
+}
+
+// AllowedCategories restricts transactions, including each split line, to the
+// given categories.
+func AllowedCategories(categories ...string) Rule[*Transaction] {
+	return Rule[*Transaction]{RuleAllowedCategories, func(t *Transaction) error {
+		for _, line := range t.Lines() {
+			if !slices.Contains(categories, line.Category) {
+				return fmt.Errorf("category %q is not allowed", line.Category)
+			}
+		}
+		return nil
+	}}
+}
+
+func MaxDescriptionLength(n int) Rule[*Transaction] {
+	return Rule[*Transaction]{RuleMaxDescription, func(t *Transaction) error {
+		if utf8.RuneCountInString(t.Description) > n {
+			return fmt.Errorf("description cannot be longer than %d characters", n)
+		}
+		return nil
+	}}
+}
+
+// AllowedBudgetCategories restricts budgets to the given categories.
+func AllowedBudgetCategories(categories ...string) Rule[*Budget] {
+	return Rule[*Budget]{RuleAllowedCategories, func(b *Budget) error {
+		if !slices.Contains(categories, b.Category) {
+			return fmt.Errorf("category %q is not allowed", b.Category)
+		}
+		return nil
+	}}
+}
+
+// Validate checks the transaction against the default policy.
+func (t *Transaction) Validate() error {
+	return defaultPolicy.Transaction.Validate(t)
+}
+
+// Validate checks the budget against the default policy.
 func (b *Budget) Validate() error {
-	if b.Limit <= 0 {
-		return errors.New("limit must be positive")
+	return defaultPolicy.Budget.Validate(b)
+}
+
+func (g *Goal) Validate() error {
+	if g.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	if g.Target <= 0 {
+		return errors.New("target must be positive")
 	}
 
-	if b.Category == "" {
+	if g.Category == "" {
 		return errors.New("category cannot be empty")
 	}
 
+	if !g.Deadline.After(g.Start) {
+		return errors.New("deadline must be after start")
+	}
+
 	return nil
 }
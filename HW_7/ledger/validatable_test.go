@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRules_WithWithout(t *testing.T) {
+	names := func(rs Rules[*Transaction]) []string {
+		var result []string
+		for _, r := range rs {
+			result = append(result, r.Name)
+		}
+		return result
+	}
+
+	base := Rules[*Transaction]{{"amount", nil}, {RuleDateWindow, nil}, {"type", nil}}
+	got := base.With(DateWindow(0, time.Hour), MaxAmount(10)).Without("type")
+	want := []string{"amount", RuleDateWindow, RuleMaxAmount}
+	if len(got) != len(want) {
+		t.Fatalf("Expected rules %v, got %v", want, names(got))
+	}
+	for i := range want {
+		if got[i].Name != want[i] {
+			t.Errorf("Expected rules %v, got %v", want, names(got))
+		}
+	}
+	if len(base) != 3 || base[1].Check != nil {
+		t.Errorf("Expected base rules to be unchanged, got %v", names(base))
+	}
+}
+
+func TestValidationPolicy_Rules(t *testing.T) {
+	now := time.Now()
+	policy := DefaultValidationPolicy()
+	policy.Transaction = policy.Transaction.With(
+		DateWindow(30*24*time.Hour, 7*24*time.Hour),
+		MaxAmount(1000),
+		AllowedCategories("food", "rent"),
+		MaxDescriptionLength(10),
+	)
+
+	tests := []struct {
+		name   string
+		tx     Transaction
+		errMsg string
+	}{
+		{"valid", Transaction{Amount: 100, Category: "food", Date: now, Type: "expense"}, ""},
+		{"scheduled", Transaction{Amount: 100, Category: "rent", Date: now.AddDate(0, 0, 3), Type: "expense"}, ""},
+		{"default rules still apply", Transaction{Amount: 0, Category: "food", Date: now, Type: "expense"}, "amount must be positive"},
+		{"too far ahead", Transaction{Amount: 100, Category: "food", Date: now.AddDate(0, 0, 8), Type: "expense"}, "date cannot be more than 7 days ahead"},
+		{"too old", Transaction{Amount: 100, Category: "food", Date: now.AddDate(0, 0, -31), Type: "expense"}, "date cannot be more than 30 days ago"},
+		{"over max amount", Transaction{Amount: 1000.01, Category: "food", Date: now, Type: "expense"}, "amount cannot exceed 1000.00"},
+		{"category not allowed", Transaction{Amount: 100, Category: "travel", Date: now, Type: "expense"}, `category "travel" is not allowed`},
+		{"split category not allowed", Transaction{Amount: 100, Category: "food", Date: now, Type: "expense", Splits: []Split{{Category: "food", Amount: 60}, {Category: "toys", Amount: 40}}}, `category "toys" is not allowed`},
+		{"description too long", Transaction{Amount: 100, Category: "food", Description: "groceries at the market", Date: now, Type: "expense"}, "description cannot be longer than 10 characters"},
+	}
+
+	for _, tt := range tests {
+		err := policy.Transaction.Validate(&tt.tx)
+		if tt.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.errMsg, err)
+		}
+	}
+}
+
+func TestLedger_SetValidationPolicy(t *testing.T) {
+	ledger := NewLedger()
+	scheduled := &Transaction{ID: "rent", Amount: 900, Category: "rent", Date: time.Now().AddDate(0, 0, 10), Type: "expense"}
+	if err := ledger.AddTransaction(scheduled); err == nil {
+		t.Fatal("Expected the default policy to reject future dates")
+	}
+
+	policy := DefaultValidationPolicy()
+	policy.Transaction = policy.Transaction.With(DateWindow(0, 31*24*time.Hour), MaxAmount(1000))
+	policy.Budget = policy.Budget.With(AllowedBudgetCategories("rent"))
+	ledger.SetValidationPolicy(policy)
+
+	if err := ledger.AddTransaction(scheduled); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ledger.UpdateTransaction(&Transaction{ID: "rent", Amount: 1200, Category: "rent", Date: time.Now(), Type: "expense"}); err == nil {
+		t.Error("Expected update over the max amount to fail")
+	}
+	if err := ledger.SetBudget(&Budget{Category: "food", Limit: 100}); err == nil {
+		t.Error("Expected budget for a category outside the policy to fail")
+	}
+	if err := ledger.SetBudget(&Budget{Category: "rent", Limit: 1000}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
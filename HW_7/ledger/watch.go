@@ -0,0 +1,51 @@
+package ledger
+
+import "sync"
+
+// budgetWatchers fans out the category of every budget change to
+// subscribers. Sends never block: a slow subscriber misses intermediate
+// notifications but will still see the latest state on its next read.
+type budgetWatchers struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan string
+}
+
+func newBudgetWatchers() *budgetWatchers {
+	return &budgetWatchers{subs: make(map[int]chan string)}
+}
+
+func (w *budgetWatchers) notify(category string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ch := range w.subs {
+		select {
+		case ch <- category:
+		default:
+		}
+	}
+}
+
+// WatchBudgets returns a channel receiving the category of each budget that
+// is set or whose spending changes, and a function that stops the watch.
+func (l *Ledger) WatchBudgets() (<-chan string, func()) {
+	w := l.watchers
+	ch := make(chan string, 16)
+
+	w.mu.Lock()
+	id := w.next
+	w.next++
+	w.subs[id] = ch
+	w.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			delete(w.subs, id)
+			w.mu.Unlock()
+			close(ch)
+		})
+	}
+}